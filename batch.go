@@ -0,0 +1,89 @@
+package spansqlx
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+)
+
+// BatchError is returned, wrapped in the *Error of the batch, when a
+// statement of a batch fails. The statements before it were executed, but
+// are rolled back with the transaction unless the error is handled inside a
+// TxPipeline.
+type BatchError struct {
+	// Index of the failed statement.
+	Index int
+	// Counts of the statements executed before it.
+	Counts []int64
+	Err    error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("statement %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch within a transaction.
+// The DML statements stmts are executed in order in a single round trip, and
+// the number of rows affected by each is returned. If a statement fails, a
+// *BatchError holds its index.
+func (d *DB) ExecBatch(ctx context.Context, stmts ...spanner.Statement) ([]int64, error) {
+	if len(stmts) == 0 {
+		return nil, nil
+	}
+
+	// checks tx in context.
+	if tx, ok := hasReadWriteTxContext(ctx); ok {
+		return batchUpdate(ctx, d, tx, stmts)
+	}
+
+	// exec the tx.
+	var counts []int64
+	if err := d.implicitTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) (err error) {
+		counts, err = batchUpdate(ctx, d, tx, stmts)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// NamedExecBatch within a transaction.
+// sql is executed once per element of arg, a slice of structs or maps whose
+// named parameters are bound as with NamedExec, in a single round trip.
+func (d *DB) NamedExecBatch(ctx context.Context, sql string, arg interface{}) ([]int64, error) {
+	stmts, err := internal.PrepareStmts(d.opts.mapper, sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	return d.ExecBatch(ctx, stmts...)
+}
+
+// batchUpdate within a transaction exec.
+func batchUpdate(ctx context.Context, db *DB, tx *spanner.ReadWriteTransaction, stmts []spanner.Statement) ([]int64, error) {
+	ctx, op, err := db.startOperation(ctx, OpBatch, tx, stmts, nil)
+	if err != nil {
+		return nil, db.endOperation(ctx, op, 0, err)
+	}
+	stmts = op.Statements
+
+	counts, err := tx.BatchUpdateWithOptions(ctx, stmts, queryOptions(ctx))
+	var rows int64
+	for _, n := range counts {
+		rows += n
+	}
+	// counts are returned up to the failed statement.
+	if err != nil && len(counts) < len(stmts) {
+		err = &BatchError{Index: len(counts), Counts: counts, Err: err}
+	}
+	if err = db.endOperation(ctx, op, rows, err); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
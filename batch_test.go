@@ -0,0 +1,63 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestExecBatch(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	counts, err := db.ExecBatch(ctx,
+		spanner.Statement{SQL: `UPDATE Albums SET AlbumTitle = 'Batch' WHERE SingerID = @id`, Params: map[string]interface{}{"id": int64(1)}},
+		spanner.Statement{SQL: `DELETE FROM Albums WHERE SingerID = @id`, Params: map[string]interface{}{"id": int64(99)}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0] != 2 || counts[1] != 0 {
+		t.Fatalf("got counts %v", counts)
+	}
+
+	// joins the transaction of the pipeline
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		counts, err = db.NamedExecBatch(ctx, `UPDATE Singers SET LastName = @LastName WHERE SingerID = @SingerID`, []Singer{
+			{SingerID: 1, LastName: "One"},
+			{SingerID: 2, LastName: "Two"},
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0] != 1 || counts[1] != 1 {
+		t.Fatalf("got counts %v", counts)
+	}
+
+	var names []string
+	if err := db.Select(ctx, &names, `SELECT LastName FROM Singers WHERE SingerID < 3 ORDER BY SingerID`); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "One,Two" {
+		t.Fatalf("got names %v", names)
+	}
+
+	_, err = db.ExecBatch(ctx,
+		spanner.NewStatement(`UPDATE Albums SET AlbumTitle = 'Batch' WHERE SingerID = 2`),
+		spanner.NewStatement(`UPDATE Missing SET Name = 'x' WHERE true`),
+	)
+	var batchErr *spansqlx.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || len(batchErr.Counts) != 1 {
+		t.Fatalf("got error %v", err)
+	}
+
+	if _, err := db.NamedExecBatch(ctx, `UPDATE Singers SET LastName = @LastName WHERE SingerID = @SingerID`, Singer{}); err == nil {
+		t.Fatal("expected batch argument error")
+	}
+}
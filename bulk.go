@@ -0,0 +1,304 @@
+package spansqlx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+)
+
+const (
+	// DefaultBulkMaxMutations is the spanner limit of mutations (cells) in a
+	// commit.
+	DefaultBulkMaxMutations = 80000
+	// DefaultBulkMaxBytes keeps commits well under the 100MiB spanner limit,
+	// as the size of a mutation is an estimate.
+	DefaultBulkMaxBytes = 64 << 20
+	// DefaultBulkConcurrency is the number of commits run at once.
+	DefaultBulkConcurrency = 4
+)
+
+// BulkOption configures a BulkWriter.
+type BulkOption func(*bulkOptions)
+
+type bulkOptions struct {
+	maxMutations int
+	maxBytes     int
+	concurrency  int
+	indexes      map[string][][]string
+	progress     func(BatchResult)
+}
+
+// WithBulkMaxMutations sets the maximum number of mutations (cells) of a
+// commit, DefaultBulkMaxMutations by default.
+func WithBulkMaxMutations(n int) BulkOption {
+	return func(o *bulkOptions) {
+		if n > 0 {
+			o.maxMutations = n
+		}
+	}
+}
+
+// WithBulkMaxBytes sets the maximum estimated size of a commit,
+// DefaultBulkMaxBytes by default.
+func WithBulkMaxBytes(n int) BulkOption {
+	return func(o *bulkOptions) {
+		if n > 0 {
+			o.maxBytes = n
+		}
+	}
+}
+
+// WithBulkConcurrency sets the number of commits run at once,
+// DefaultBulkConcurrency by default. With more than one, batches may commit
+// in any order.
+func WithBulkConcurrency(n int) BulkOption {
+	return func(o *bulkOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithBulkIndex declares a secondary index of table over columns, key and
+// storing columns included. Spanner counts the index entries of a mutation
+// against the commit limit, so every index of the written tables should be
+// declared.
+func WithBulkIndex(table string, columns ...string) BulkOption {
+	return func(o *bulkOptions) {
+		o.indexes[table] = append(o.indexes[table], columns)
+	}
+}
+
+// WithBulkProgress calls fn once per batch, when its commit is done. Calls
+// are serialized.
+func WithBulkProgress(fn func(BatchResult)) BulkOption {
+	return func(o *bulkOptions) {
+		o.progress = fn
+	}
+}
+
+// BatchResult is the outcome of a commit of a BulkWriter.
+type BatchResult struct {
+	// Batch is the sequence number of the batch, starting at 1.
+	Batch int
+	// Mutations is the number of spanner.Mutation of the batch.
+	Mutations int
+	// Cells is the number of mutations as counted by the commit limits.
+	Cells int
+	// Bytes is the estimated size of the batch.
+	Bytes           int
+	CommitTimestamp time.Time
+	Err             error
+}
+
+// BulkError is returned by BulkWriter.Close when some batches failed.
+type BulkError struct {
+	Failed []BatchResult
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("spansqlx: %d bulk batches failed, batch %d: %v", len(e.Failed), e.Failed[0].Batch, e.Failed[0].Err)
+}
+
+// Unwrap returns the error of the first failed batch.
+func (e *BulkError) Unwrap() error {
+	return e.Failed[0].Err
+}
+
+// BulkWriter writes large amounts of mutations, split into as many commits
+// as needed to stay under the spanner commit limits. Each commit is a
+// transaction of its own, even inside a TxPipeline.
+//
+//	w := db.NewBulkWriter(ctx)
+//	if err := w.Insert("Singers", singers); err != nil {
+//		return err
+//	}
+//	results, err := w.Close()
+type BulkWriter struct {
+	db   *DB
+	ctx  context.Context
+	opts bulkOptions
+
+	// mu guards the pending batch, it is held while waiting for a commit
+	// slot.
+	mu     sync.Mutex
+	batch  []*spanner.Mutation
+	cells  int
+	bytes  int
+	closed bool
+
+	// resultsMu guards the results and serializes the progress calls.
+	resultsMu sync.Mutex
+	results   []BatchResult
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// NewBulkWriter returns a BulkWriter committing with ctx.
+func (d *DB) NewBulkWriter(ctx context.Context, opts ...BulkOption) *BulkWriter {
+	o := bulkOptions{
+		maxMutations: DefaultBulkMaxMutations,
+		maxBytes:     DefaultBulkMaxBytes,
+		concurrency:  DefaultBulkConcurrency,
+		indexes:      make(map[string][][]string),
+	}
+	for i := range opts {
+		opts[i](&o)
+	}
+
+	return &BulkWriter{
+		db:   d,
+		ctx:  ctx,
+		opts: o,
+		sem:  make(chan struct{}, o.concurrency),
+	}
+}
+
+// Insert rows into table, as DB.Insert does.
+func (w *BulkWriter) Insert(table string, arg interface{}, opts ...MutationOption) error {
+	return w.mutate(spanner.Insert, table, arg, opts)
+}
+
+// Update rows of table, as DB.Update does.
+func (w *BulkWriter) Update(table string, arg interface{}, opts ...MutationOption) error {
+	return w.mutate(spanner.Update, table, arg, opts)
+}
+
+// InsertOrUpdate rows of table, as DB.InsertOrUpdate does.
+func (w *BulkWriter) InsertOrUpdate(table string, arg interface{}, opts ...MutationOption) error {
+	return w.mutate(spanner.InsertOrUpdate, table, arg, opts)
+}
+
+// Replace rows of table, as DB.Replace does.
+func (w *BulkWriter) Replace(table string, arg interface{}, opts ...MutationOption) error {
+	return w.mutate(spanner.Replace, table, arg, opts)
+}
+
+// Delete the rows of table at keys.
+func (w *BulkWriter) Delete(table string, keys spanner.KeySet) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.write(spanner.Delete(table, keys), internal.MutationCells(nil, w.opts.indexes[table]),
+		internal.MutationSize(table, nil, keys))
+}
+
+// Write adds a mutation of op, such as spanner.Insert, writing values to
+// columns of table. It is for rows which are neither structs nor maps, the
+// mutation is counted against the commit limits as Insert does.
+func (w *BulkWriter) Write(op func(table string, columns []string, values []interface{}) *spanner.Mutation, table string, columns []string, values []interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.write(op(table, columns, values), internal.MutationCells(columns, w.opts.indexes[table]),
+		internal.MutationSize(table, columns, values))
+}
+
+// write adds the mutation mu of cells and size, w.mu must be held. A batch
+// is committed in the background as soon as it is full, write blocks while
+// all commits are running.
+func (w *BulkWriter) write(mu *spanner.Mutation, cells, size int) error {
+	if w.closed {
+		return fmt.Errorf("spansqlx: bulk writer is closed")
+	}
+	if cells > w.opts.maxMutations || size > w.opts.maxBytes {
+		return fmt.Errorf("spansqlx: mutation of %d cells and %d bytes exceeds the commit limits", cells, size)
+	}
+
+	if w.cells+cells > w.opts.maxMutations || w.bytes+size > w.opts.maxBytes {
+		w.flush()
+	}
+
+	w.batch = append(w.batch, mu)
+	w.cells += cells
+	w.bytes += size
+	return nil
+}
+
+// Close commits the pending mutations and waits for every commit. It returns
+// the results of all batches in order, and a *BulkError if any failed.
+func (w *BulkWriter) Close() ([]BatchResult, error) {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		w.flush()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	w.resultsMu.Lock()
+	defer w.resultsMu.Unlock()
+
+	var failed []BatchResult
+	for _, r := range w.results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		return w.results, &BulkError{Failed: failed}
+	}
+	return w.results, nil
+}
+
+// flush commits the current batch in the background, w.mu must be held.
+func (w *BulkWriter) flush() {
+	if len(w.batch) == 0 {
+		return
+	}
+
+	w.resultsMu.Lock()
+	i := len(w.results)
+	w.results = append(w.results, BatchResult{
+		Batch:     i + 1,
+		Mutations: len(w.batch),
+		Cells:     w.cells,
+		Bytes:     w.bytes,
+	})
+	w.resultsMu.Unlock()
+
+	batch := w.batch
+	w.batch, w.cells, w.bytes = nil, 0, 0
+
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.sem }()
+
+		ts, err := w.db.apply(w.ctx, batch)
+
+		w.resultsMu.Lock()
+		defer w.resultsMu.Unlock()
+		w.results[i].CommitTimestamp = ts
+		w.results[i].Err = err
+		if w.opts.progress != nil {
+			w.opts.progress(w.results[i])
+		}
+	}()
+}
+
+// mutate builds the mutations of op from arg and writes them.
+func (w *BulkWriter) mutate(op internal.MutationOp, table string, arg interface{}, opts []MutationOption) error {
+	ms, err := w.db.prepareMutations(op, table, arg, opts)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, mu := range ms {
+		cells := internal.MutationCells(mu.Columns, w.opts.indexes[table])
+		if err := w.write(mu.Mutation, cells, internal.MutationSize(table, mu.Columns, mu.Values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
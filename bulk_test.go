@@ -0,0 +1,97 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestBulkWriter(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	var (
+		mu       sync.Mutex
+		progress int
+	)
+	// 3 columns and an index of 2 columns per album, 2 albums per batch.
+	w := db.NewBulkWriter(ctx,
+		spansqlx.WithBulkMaxMutations(10),
+		spansqlx.WithBulkConcurrency(2),
+		spansqlx.WithBulkIndex("Albums", "AlbumTitle", "SingerID"),
+		spansqlx.WithBulkProgress(func(r spansqlx.BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress++
+		}),
+	)
+
+	var albums []Album
+	for i := 1; i <= 7; i++ {
+		albums = append(albums, Album{SingerID: 10, AlbumID: int64(i), AlbumTitle: fmt.Sprint("Bulk ", i)})
+	}
+	if err := w.Insert("Albums", albums); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Delete("Albums", spanner.Key{10, 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || progress != 4 {
+		t.Fatalf("got %d batches and %d progress calls, want 4", len(results), progress)
+	}
+	for i, r := range results {
+		if r.Batch != i+1 || r.Cells > 10 || r.CommitTimestamp.IsZero() {
+			t.Fatalf("got batch %+v", r)
+		}
+	}
+
+	var n int64
+	if err := db.Get(ctx, &n, `SELECT COUNT(*) FROM Albums WHERE SingerID = 10`); err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Fatalf("got %d albums, want 6", n)
+	}
+
+	if err := w.Insert("Albums", albums); err == nil {
+		t.Fatal("expected closed writer error")
+	}
+
+	// a single mutation over the limits
+	w = db.NewBulkWriter(ctx, spansqlx.WithBulkMaxMutations(2))
+	if err := w.Insert("Albums", albums[0]); err == nil {
+		t.Fatal("expected limit error")
+	}
+	// mutations of columns and values are counted as those of structs.
+	columns := []string{"SingerID", "AlbumID", "AlbumTitle"}
+	if err := w.Write(spanner.Insert, "Albums", columns, []interface{}{10, 8, "Raw"}); err == nil {
+		t.Fatal("expected limit error")
+	}
+	if err := w.Write(spanner.Insert, "Albums", columns[:2], []interface{}{10, 8}); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := w.Close(); err != nil || len(results) != 1 || results[0].Cells != 2 || results[0].Bytes == 0 {
+		t.Fatalf("got results %+v, error %v", results, err)
+	}
+
+	// the failed batch is reported
+	w = db.NewBulkWriter(ctx)
+	if err := w.Insert("Albums", albums[0]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Close()
+	var bulkErr *spansqlx.BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr.Failed) != 1 {
+		t.Fatalf("got error %v", err)
+	}
+}
@@ -13,6 +13,16 @@ const (
 	rwTxContextKey txContextKey = iota + 1
 	// ReadOnly transaction
 	roTxContextKey
+	// Single read options
+	readOptionsContextKey
+	// ID of the read-write transaction
+	txIDContextKey
+	// Attempt of the read-write transaction
+	attemptContextKey
+	// Request tag of statements
+	requestTagContextKey
+	// Attempt of a TxPipeline
+	txPipelineContextKey
 )
 
 func SetTxContext(ctx context.Context, arg interface{}) context.Context {
@@ -48,7 +58,7 @@ func hasReadOnlyTxContext(ctx context.Context) (*spanner.ReadOnlyTransaction, bo
 	if ctx == nil {
 		return nil, false
 	}
-	tx, ok := ctx.Value(rwTxContextKey).(*spanner.ReadOnlyTransaction)
+	tx, ok := ctx.Value(roTxContextKey).(*spanner.ReadOnlyTransaction)
 	if !ok {
 		return nil, false
 	}
@@ -68,3 +78,47 @@ func hasTxContext(ctx context.Context) interface{} {
 	}
 	return nil
 }
+
+// txIDContext returns the id of the read-write transaction of ctx, or ""
+// outside of a transaction run by a DB.
+func txIDContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(txIDContextKey).(string)
+	return id
+}
+
+// attemptContext returns the attempt of the read-write transaction of ctx,
+// starting at 1, or 0 outside of a transaction run by a DB.
+func attemptContext(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	n, _ := ctx.Value(attemptContextKey).(int)
+	return n
+}
+
+// SetRequestTag attaches the spanner request tag to ctx. It tags the queries
+// and DML statements made with ctx, and is the RequestTag of their
+// operations.
+func SetRequestTag(ctx context.Context, tag string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestTagContextKey, tag)
+}
+
+// requestTagContext returns the request tag attached to ctx.
+func requestTagContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tag, _ := ctx.Value(requestTagContextKey).(string)
+	return tag
+}
+
+// queryOptions returns the query options of the statements made with ctx.
+func queryOptions(ctx context.Context) spanner.QueryOptions {
+	return spanner.QueryOptions{RequestTag: requestTagContext(ctx)}
+}
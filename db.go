@@ -2,24 +2,23 @@ package spansqlx
 
 import (
 	"context"
-	"errors"
-	"log"
 
 	"cloud.google.com/go/spanner"
 	"github.com/reiot101/spansqlx/internal"
+	"github.com/reiot101/spansqlx/reflectx"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
-var (
-	ErrBadConn = errors.New("scansqlx: bad connection")
-	ErrNoRows  = errors.New("scansqlx: no rows")
-)
-
 type Options struct {
 	database      string
 	clientOptions []option.ClientOption
 	clientConfig  *spanner.ClientConfig
+	mapper        *reflectx.Mapper
+	logger        Logger
+	logLevel      LogLevel
+	redactor      Redactor
+	interceptors  []Interceptor
 }
 
 type Option func(*Options) error
@@ -45,6 +44,24 @@ func WithClientConfig(s *spanner.ClientConfig) Option {
 	}
 }
 
+// WithMapper sets the mapper used to match struct fields to column and
+// parameter names. The default mapper honours the `spanner` and `db` tags and
+// uses the Go field name for untagged fields.
+func WithMapper(m *reflectx.Mapper) Option {
+	return func(o *Options) error {
+		if m != nil {
+			o.mapper = m
+		}
+		return nil
+	}
+}
+
+// WithNameMapper is a shorthand of WithMapper using the `spanner` and `db`
+// tags, and mapFunc for untagged fields, e.g. reflectx.SnakeCase.
+func WithNameMapper(mapFunc reflectx.NameMapper) Option {
+	return WithMapper(reflectx.NewMapperFunc(mapFunc, "spanner", "db"))
+}
+
 // DB is a wrapper around spanner.Client which keeps track of the options upon Open,
 // used mostly to automatically bind named queries using the right bindvars.
 type DB struct {
@@ -54,29 +71,47 @@ type DB struct {
 
 // Open is the same as spanner.NewClient, but returns an *spansql.DB instead.
 func Open(ctx context.Context, opts ...Option) (*DB, error) {
+	options, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{opts: options}
+	if err := db.open(ctx); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewDb returns an DB instance.
+// Options which would configure the spanner client are ignored.
+func NewDb(ctx context.Context, db *spanner.Client, opts ...Option) *DB {
+	// options which do not open a client never fail.
+	options, _ := newOptions(opts...)
+
+	return &DB{opts: options, db: db}
+}
+
+// newOptions applies opts over the default options.
+func newOptions(opts ...Option) (Options, error) {
 	// default options
 	options := Options{
 		database:      "projects/sandbox/instances/sandbox/databases/sandbox",
 		clientOptions: []option.ClientOption{},
 		clientConfig:  nil,
+		mapper:        reflectx.NewMapper("spanner", "db"),
+		logLevel:      LogDebug,
+		redactor:      RedactAll,
 	}
 
 	// apply options
 	for i := range opts {
 		if err := opts[i](&options); err != nil {
-			return nil, err
+			return Options{}, err
 		}
 	}
 
-	db := &DB{opts: options}
-	return db, db.open(ctx)
-}
-
-// NewDb returns an DB instance.
-func NewDb(ctx context.Context, db *spanner.Client) *DB {
-	return &DB{
-		db: db,
-	}
+	return options, nil
 }
 
 // open spanner database connection
@@ -102,7 +137,11 @@ func (d *DB) open(ctx context.Context) error {
 	}
 
 	d.db = db
-	return d.Ping(ctx)
+	if err := d.Ping(ctx); err != nil {
+		db.Close()
+		return err
+	}
+	return nil
 }
 
 // Ping to a database and verify.
@@ -118,122 +157,78 @@ func (d *DB) Ping(ctx context.Context) error {
 }
 
 // Select within a transaction.
-// Any placeholder parameters are replaced with supplied args.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
+// Rows are scanned into dest as they are streamed from spanner.
 func (d *DB) Select(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
-	rows, err := d.Query(ctx, sql, args...)
+	args, opts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
 	if err != nil {
 		return err
 	}
-	return internal.ScanAll(rows, dest)
+	return d.SelectX(ctx, dest, stmt, opts...)
 }
 
 // SelectX within a transaction.
 // Based spanner statement.
-func (d *DB) SelectX(ctx context.Context, dest interface{}, stmt spanner.Statement) error {
-	rows, err := d.QueryX(ctx, stmt)
-	if err != nil {
-		return err
-	}
-	return internal.ScanAll(rows, dest)
+func (d *DB) SelectX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return internal.ScanIter(d.opts.mapper, query(ctx, d, stmt, opts...), dest)
 }
 
 // Get within a transaction.
-// Any placeholder parameters are replaced with supplied args.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
 // An error is returned if the result set is empty.
 func (d *DB) Get(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
-	var row *spanner.Row
+	args, opts := splitArgs(args)
 
 	stmt, err := internal.PrepareStmtAll(sql, args...)
 	if err != nil {
 		return err
 	}
-
-	err = forEach(ctx, d.db, func(iter *spanner.RowIterator) error {
-		defer iter.Stop()
-
-		if v, err := iter.Next(); err != nil && err != iterator.Done {
-			return err
-		} else {
-			row = v
-		}
-
-		return nil
-	}, stmt)
-
-	if err != nil {
-		return err
-	}
-
-	if row == nil {
-		return ErrNoRows
-	}
-
-	return internal.ScanAny(row, dest)
+	return d.GetX(ctx, dest, stmt, opts...)
 }
 
 // GetX within a transaction.
 // Based spanner statement.
 // An error is returned if the result set is empty.
-func (d *DB) GetX(ctx context.Context, dest interface{}, stmt spanner.Statement) error {
-	var row *spanner.Row
-
-	err := forEach(ctx, d.db, func(iter *spanner.RowIterator) error {
-		defer iter.Stop()
-
-		if v, err := iter.Next(); err != nil && err != iterator.Done {
-			return err
-		} else {
-			row = v
-		}
-
-		return nil
-	}, stmt)
-
-	if err != nil {
-		return err
-	}
-
-	if row == nil {
-		return ErrNoRows
-	}
-
-	return internal.ScanAny(row, dest)
+func (d *DB) GetX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return d.get(ctx, dest, stmt, false, opts...)
 }
 
 // Query queries the database and returns an *spanner.Row slice.
-// Any placeholder parameters are replaced with supplied args.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
+// The whole result set is buffered, use QueryRows to stream large results.
 func (d *DB) Query(ctx context.Context, sql string, args ...interface{}) ([]*spanner.Row, error) {
-	var rows []*spanner.Row
+	args, opts := splitArgs(args)
 
 	stmt, err := internal.PrepareStmtAll(sql, args...)
 	if err != nil {
 		return nil, err
 	}
-
-	err = forEach(ctx, d.db, func(iter *spanner.RowIterator) error {
-		return iter.Do(func(row *spanner.Row) error {
-			rows = append(rows, row)
-			return nil
-		})
-	}, stmt)
-	if err != nil {
-		return nil, err
-	}
-
-	return rows, nil
+	return d.QueryX(ctx, stmt, opts...)
 }
 
 // QueryX queries the database and returns an *spanner.Row slice.
 // Based spanner statement.
-func (d *DB) QueryX(ctx context.Context, stmt spanner.Statement) ([]*spanner.Row, error) {
+// The whole result set is buffered, use QueryRowsX to stream large results.
+func (d *DB) QueryX(ctx context.Context, stmt spanner.Statement, opts ...ReadOption) ([]*spanner.Row, error) {
 	var rows []*spanner.Row
 
-	err := forEach(ctx, d.db, func(iter *spanner.RowIterator) error {
-		return iter.Do(func(row *spanner.Row) error {
+	err := forEach(ctx, d, func(iter *rowIter) error {
+		for {
+			row, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
 			rows = append(rows, row)
-			return nil
-		})
-	}, stmt)
+		}
+	}, stmt, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -241,62 +236,55 @@ func (d *DB) QueryX(ctx context.Context, stmt spanner.Statement) ([]*spanner.Row
 	return rows, nil
 }
 
-func (d *DB) Exec(ctx context.Context, sql string, args ...interface{}) error {
+// Exec within a transaction.
+// Any placeholder parameters are replaced with supplied args, ExecOption
+// values among args check the result of the statement.
+func (d *DB) Exec(ctx context.Context, sql string, args ...interface{}) (Result, error) {
+	args, opts := splitExecArgs(args)
+
 	stmt, err := internal.PrepareStmtAll(sql, args...)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
-
-	// checks tx in context.
-	if tx, ok := hasReadWriteTxContext(ctx); ok {
-		return update(ctx, tx, stmt)
-	}
-
-	// exec the tx.
-	if _, err := d.db.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
-		return update(ctx, tx, stmt)
-	}); err != nil {
-		return nil
-	}
-
-	return nil
+	return d.ExecX(ctx, stmt, opts...)
 }
 
-func (d *DB) ExecX(ctx context.Context, stmt spanner.Statement) error {
+// ExecX within a transaction.
+// Based spanner statement.
+func (d *DB) ExecX(ctx context.Context, stmt spanner.Statement, opts ...ExecOption) (Result, error) {
+	o := newExecOptions(opts...)
+
 	// checks tx in context.
 	if tx, ok := hasReadWriteTxContext(ctx); ok {
-		return update(ctx, tx, stmt)
+		return update(ctx, d, tx, stmt, o)
 	}
 
 	// exec the tx.
-	if _, err := d.db.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
-		return update(ctx, tx, stmt)
+	var res Result
+	if err := d.implicitTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) (err error) {
+		res, err = update(ctx, d, tx, stmt, o)
+		return err
 	}); err != nil {
-		return nil
+		return Result{}, err
 	}
 
-	return nil
+	return res, nil
 }
 
-func (d *DB) NamedExec(ctx context.Context, sql string, arg interface{}) error {
-	stmt, err := internal.PrepareStmtAny(sql, arg)
+// NamedExec within a transaction.
+// Named parameters are bound from the fields of a struct or the keys of a
+// map.
+func (d *DB) NamedExec(ctx context.Context, sql string, arg interface{}, opts ...ExecOption) (Result, error) {
+	stmt, err := internal.PrepareStmtAny(d.opts.mapper, sql, arg)
 	if err != nil {
-		return err
-	}
-
-	// checks tx in context.
-	if tx, ok := hasReadWriteTxContext(ctx); ok {
-		return update(ctx, tx, stmt)
-	}
-
-	// exec the tx.
-	if _, err := d.db.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
-		return update(ctx, tx, stmt)
-	}); err != nil {
-		return err
+		return Result{}, err
 	}
+	return d.ExecX(ctx, stmt, opts...)
+}
 
-	return nil
+// Client returns the spanner client of the database.
+func (d *DB) Client() *spanner.Client {
+	return d.db
 }
 
 // Close the database connection
@@ -307,39 +295,56 @@ func (d *DB) Close() error {
 	return nil
 }
 
-// TxPipeline is ReadWriteTransaction wrap.
-func (d *DB) TxPipeline(ctx context.Context, callback func(ctx context.Context) error) error {
-	_, err := d.db.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
-		return callback(SetTxContext(ctx, tx))
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// forEach within a transaction with row iterator
+func forEach(ctx context.Context, db *DB, fn func(*rowIter) error, stmt spanner.Statement, opts ...ReadOption) error {
+	iter := query(ctx, db, stmt, opts...)
+	defer iter.Stop()
+
+	return fn(iter)
 }
 
-// forEach within a transaction with row iterator
-func forEach(ctx context.Context, db *spanner.Client, fn func(*spanner.RowIterator) error, stmt spanner.Statement) error {
-	var it *spanner.RowIterator
+// query within a transaction returns the row iterator of stmt.
+// The read options of ctx and opts only apply to single reads, a transaction
+// reads from its own snapshot.
+func query(ctx context.Context, db *DB, stmt spanner.Statement, opts ...ReadOption) *rowIter {
+	tx := hasTxContext(ctx)
+	ctx, op, err := db.startOperation(ctx, OpQuery, tx, []spanner.Statement{stmt}, nil)
+	if err != nil {
+		return newRowIter(ctx, db, op, nil, err)
+	}
+	stmt = op.Statements[0]
 
-	switch tx := hasTxContext(ctx).(type) {
+	var iter *spanner.RowIterator
+	switch tx := tx.(type) {
 	case *spanner.ReadOnlyTransaction:
-		it = tx.Query(ctx, stmt)
+		iter = tx.QueryWithOptions(ctx, stmt, queryOptions(ctx))
 	case *spanner.ReadWriteTransaction:
-		it = tx.Query(ctx, stmt)
+		iter = tx.QueryWithOptions(ctx, stmt, queryOptions(ctx))
 	default:
-		it = db.Single().Query(ctx, stmt)
+		single := db.db.Single()
+		if o := newReadOptions(append(readOptionsContext(ctx), opts...)...); o.bound != nil {
+			single = single.WithTimestampBound(*o.bound)
+		}
+		iter = single.QueryWithOptions(ctx, stmt, queryOptions(ctx))
 	}
 
-	return fn(it)
+	return newRowIter(ctx, db, op, iter, nil)
 }
 
 // update within a transaction exec.
-func update(ctx context.Context, tx *spanner.ReadWriteTransaction, stmt spanner.Statement) error {
-	row, err := tx.Update(ctx, stmt)
+// An error fails the transaction when the affected rows are not expected.
+func update(ctx context.Context, db *DB, tx *spanner.ReadWriteTransaction, stmt spanner.Statement, o execOptions) (Result, error) {
+	ctx, op, err := db.startOperation(ctx, OpExec, tx, []spanner.Statement{stmt}, nil)
+	var row int64
+	if err == nil {
+		row, err = tx.UpdateWithOptions(ctx, op.Statements[0], queryOptions(ctx))
+	}
+	if err == nil && o.expectRows != nil && *o.expectRows != row {
+		err = &RowsAffectedError{Expected: *o.expectRows, Actual: row}
+	}
+	err = db.endOperation(ctx, op, row, err)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
-	log.Printf("update record(%d)s", row)
-	return nil
+	return Result{RowsAffected: row}, nil
 }
@@ -0,0 +1,130 @@
+package spansqlx_test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal/spantest"
+	"github.com/reiot101/spansqlx/reflectx"
+)
+
+const testSchema = `
+CREATE TABLE Singers (
+	SingerID INT64 NOT NULL,
+	FirstName STRING(1024),
+	LastName STRING(1024),
+) PRIMARY KEY (SingerID);
+CREATE TABLE Albums (
+	SingerID INT64 NOT NULL,
+	AlbumID INT64 NOT NULL,
+	AlbumTitle STRING(MAX),
+) PRIMARY KEY (SingerID, AlbumID);
+CREATE TABLE Events (
+	EventID INT64 NOT NULL,
+	Name STRING(MAX),
+	UpdatedAt TIMESTAMP OPTIONS (allow_commit_timestamp = true),
+) PRIMARY KEY (EventID)`
+
+// newTestDB returns a *spansqlx.DB connected to an in-memory spanner fake
+// seeded with allSingers and allAlbums.
+func newTestDB(t *testing.T) (*spansqlx.DB, *spanner.Client) {
+	t.Helper()
+
+	client := spantest.NewClient(t, testSchema)
+
+	var ms []*spanner.Mutation
+	for _, s := range allSingers {
+		ms = append(ms, spanner.Insert("Singers",
+			[]string{"SingerID", "FirstName", "LastName"},
+			[]interface{}{s.SingerID, s.FirstName, s.LastName}))
+	}
+	for _, a := range allAlbums {
+		ms = append(ms, spanner.Insert("Albums",
+			[]string{"SingerID", "AlbumID", "AlbumTitle"},
+			[]interface{}{a.SingerID, a.AlbumID, a.AlbumTitle}))
+	}
+	if _, err := client.Apply(context.Background(), ms); err != nil {
+		t.Fatal(err)
+	}
+
+	return spansqlx.NewDb(context.Background(), client), client
+}
+
+func TestSelect(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	var singers []*Singer
+	if err := db.Select(context.Background(), &singers,
+		`SELECT SingerID, FirstName, LastName FROM Singers ORDER BY SingerID`); err != nil {
+		t.Fatal(err)
+	}
+	if len(singers) != len(allSingers) {
+		t.Fatalf("got %d singers, want %d", len(singers), len(allSingers))
+	}
+	for i := range singers {
+		if *singers[i] != allSingers[i] {
+			t.Errorf("singers[%d] = %+v, want %+v", i, singers[i], allSingers[i])
+		}
+	}
+}
+
+func TestMapper(t *testing.T) {
+	_, client := newTestDB(t)
+	db := spansqlx.NewDb(context.Background(), client, spansqlx.WithNameMapper(reflectx.SnakeCase))
+
+	type singer struct {
+		ID        int64 `spanner:"SingerID"`
+		FirstName string
+		Last      string `db:"LastName"`
+	}
+
+	_, err := db.NamedExec(context.Background(),
+		`UPDATE Singers SET FirstName = @first_name WHERE SingerID = @SingerID`,
+		singer{ID: 1, FirstName: "Mark"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got singer
+	if err := db.Get(context.Background(), &got,
+		`SELECT SingerID, FirstName AS first_name, LastName FROM Singers WHERE SingerID = @id`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := (singer{ID: 1, FirstName: "Mark", Last: "Richards"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	type person struct {
+		FirstName string
+		LastName  string
+	}
+	type singerKey struct {
+		SingerID int64
+	}
+	type singer struct {
+		singerKey
+		Name person `db:"name"`
+	}
+
+	_, err := db.NamedExec(context.Background(),
+		`UPDATE Singers SET FirstName = @name_FirstName WHERE SingerID = @SingerID`,
+		singer{singerKey{2}, person{FirstName: "Cat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got singer
+	if err := db.Get(context.Background(), &got,
+		"SELECT SingerID, FirstName AS `name.FirstName`, LastName AS `name.LastName` FROM Singers WHERE SingerID = @id", 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := (singer{singerKey{2}, person{"Cat", "Smith"}}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
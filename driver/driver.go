@@ -0,0 +1,432 @@
+// Package driver is a database/sql driver of spanner built on spansqlx, for
+// tools which only speak database/sql.
+//
+//	db, err := sql.Open("spansqlx", "projects/p/instances/i/databases/d")
+//
+// or, sharing the client, interceptors and logger of a *spansqlx.DB:
+//
+//	db := sql.OpenDB(driver.NewConnector(sdb))
+//
+// Statements take named parameters bound with sql.Named, or positional
+// arguments bound to ? placeholders, which are rewritten to @p1, @p2, ...,
+// or to the named parameters of the statement in order of appearance.
+// Statements outside of a transaction run as DB.Exec and DB.Query do.
+// BeginTx begins a read-only transaction if sql.TxOptions.ReadOnly is set,
+// and a read-write transaction otherwise. Unlike TxPipeline, an aborted
+// read-write transaction is not retried, Commit returns the Aborted error.
+//
+// Columns are scanned as their Go types, NUMERIC and JSON columns as
+// strings. ARRAY and STRUCT columns are scanned as a
+// spanner.GenericColumnValue, into an interface{} or a sql.Scanner which
+// decodes it.
+//
+// DDL statements, CREATE, ALTER and DROP, are executed one at a time by
+// the database admin service, e.g. for migration tools. They cannot run in
+// a transaction, and are not seen by the interceptors of the DB.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal"
+	"google.golang.org/api/option"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// DriverName is the name of the driver registered with database/sql.
+const DriverName = "spansqlx"
+
+var (
+	// ErrReadOnlyTx is returned by statements executed in a read-only
+	// transaction.
+	ErrReadOnlyTx = errors.New("spansqlx: statement in a read-only transaction")
+	// ErrLastInsertID is returned by Result.LastInsertId, spanner has no
+	// auto-generated keys.
+	ErrLastInsertID = errors.New("spansqlx: LastInsertId is not supported")
+	// ErrDDLInTx is returned by DDL statements executed in a transaction,
+	// spanner applies schema changes outside of transactions.
+	ErrDDLInTx = errors.New("spansqlx: DDL statement in a transaction")
+)
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// Driver opens a *spansqlx.DB per data source name, the spanner database
+// path, shared by the connections of a sql.DB.
+type Driver struct{}
+
+// Open returns a connection to the database dsn with a client of its own,
+// closed with the connection. sql.Open uses OpenConnector instead.
+func (d *Driver) Open(dsn string) (sqldriver.Conn, error) {
+	c := &connector{driver: d, opts: []spansqlx.Option{spansqlx.WithDatabase(dsn)}}
+	cn, err := c.Connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	cn.(*conn).owner = true
+	return cn, nil
+}
+
+// OpenConnector returns a connector of the database dsn. The database is
+// opened by the first connection, and closed with the sql.DB.
+func (d *Driver) OpenConnector(dsn string) (sqldriver.Connector, error) {
+	return &connector{driver: d, opts: []spansqlx.Option{spansqlx.WithDatabase(dsn)}}, nil
+}
+
+// NewConnector returns a connector of db, for sql.OpenDB. db is not closed
+// with the sql.DB. opts are the options of the database admin client which
+// executes DDL statements, such as the options of the client of db.
+func NewConnector(db *spansqlx.DB, opts ...option.ClientOption) sqldriver.Connector {
+	return &connector{driver: &Driver{}, db: db, adminOpts: opts, shared: true}
+}
+
+type connector struct {
+	driver    *Driver
+	opts      []spansqlx.Option
+	adminOpts []option.ClientOption
+	shared    bool
+
+	mu    sync.Mutex
+	db    *spansqlx.DB
+	admin *database.DatabaseAdminClient
+}
+
+func (c *connector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		db, err := spansqlx.Open(ctx, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.db = db
+	}
+	return &conn{connector: c, db: c.db}, nil
+}
+
+func (c *connector) Driver() sqldriver.Driver {
+	return c.driver
+}
+
+// Close the database opened by the connector, and its database admin
+// client.
+func (c *connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	if c.admin != nil {
+		err = c.admin.Close()
+		c.admin = nil
+	}
+	if c.shared || c.db == nil {
+		return err
+	}
+	if cerr := c.db.Close(); err == nil {
+		err = cerr
+	}
+	c.db = nil
+	return err
+}
+
+// updateDDL executes the DDL statement, with a database admin client opened
+// by the first DDL statement of the connector.
+func (c *connector) updateDDL(ctx context.Context, statement string) error {
+	c.mu.Lock()
+	if c.admin == nil {
+		admin, err := database.NewDatabaseAdminClient(ctx, c.adminOpts...)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.admin = admin
+	}
+	admin := c.admin
+	c.mu.Unlock()
+
+	op, err := admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   c.db.Client().DatabaseName(),
+		Statements: []string{statement},
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+// conn is a connection of a sql.DB. Connections share the sessions of the
+// spanner client, they only hold the transaction begun on them.
+type conn struct {
+	connector *connector
+	db        *spansqlx.DB
+	tx        *tx
+	// owner is set if the connection was opened by Driver.Open, the
+	// connector is closed with it.
+	owner bool
+}
+
+var (
+	_ sqldriver.ConnBeginTx        = (*conn)(nil)
+	_ sqldriver.ConnPrepareContext = (*conn)(nil)
+	_ sqldriver.ExecerContext      = (*conn)(nil)
+	_ sqldriver.QueryerContext     = (*conn)(nil)
+	_ sqldriver.NamedValueChecker  = (*conn)(nil)
+	_ sqldriver.Pinger             = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (sqldriver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement of query. Spanner caches query plans
+// on its own, the statement is only prepared when executed.
+func (c *conn) PrepareContext(ctx context.Context, query string) (sqldriver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	var err error
+	if c.tx != nil {
+		err = c.tx.Rollback()
+	}
+	if c.owner {
+		if cerr := c.connector.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (c *conn) Begin() (sqldriver.Tx, error) {
+	return c.BeginTx(context.Background(), sqldriver.TxOptions{})
+}
+
+// BeginTx begins a read-only transaction, reading strongly, if opts is read
+// only, and a read-write transaction otherwise. Spanner transactions are
+// serializable.
+func (c *conn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("spansqlx: transaction already begun")
+	}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault, sql.LevelSerializable:
+	default:
+		return nil, fmt.Errorf("spansqlx: unsupported isolation level %s", sql.IsolationLevel(opts.Isolation))
+	}
+
+	t := &tx{conn: c}
+	if opts.ReadOnly {
+		t.ro = c.db.Client().ReadOnlyTransaction()
+	} else {
+		rw, err := spanner.NewReadWriteStmtBasedTransaction(ctx, c.db.Client())
+		if err != nil {
+			return nil, err
+		}
+		t.rw = rw
+	}
+	c.tx = t
+	return t, nil
+}
+
+// txContext returns ctx with the transaction of the connection, if any.
+func (c *conn) txContext(ctx context.Context) context.Context {
+	switch {
+	case c.tx == nil:
+		return ctx
+	case c.tx.ro != nil:
+		return spansqlx.SetTxContext(ctx, c.tx.ro)
+	default:
+		return spansqlx.SetTxContext(ctx, &c.tx.rw.ReadWriteTransaction)
+	}
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	if isDDL(query) {
+		if c.tx != nil {
+			return nil, ErrDDLInTx
+		}
+		if len(args) > 0 {
+			return nil, errors.New("spansqlx: DDL statements take no arguments")
+		}
+		if err := c.connector.updateDDL(ctx, query); err != nil {
+			return nil, err
+		}
+		return result{}, nil
+	}
+
+	if c.tx != nil && c.tx.ro != nil {
+		return nil, ErrReadOnlyTx
+	}
+
+	stmt, err := statement(query, args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.db.ExecX(c.txContext(ctx), stmt)
+	if err != nil {
+		return nil, err
+	}
+	return result{rowsAffected: res.RowsAffected}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	stmt, err := statement(query, args)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.db.QueryRowsX(c.txContext(ctx), stmt)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(r)
+}
+
+// CheckNamedValue accepts every value, which are bound to the statement as
+// spanner parameters, e.g. slices as arrays or spanner.NullString.
+func (c *conn) CheckNamedValue(*sqldriver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.db.Ping(ctx)
+}
+
+// isDDL reports whether query is a DDL statement.
+func isDDL(query string) bool {
+	switch keyword, _ := internal.StatementKeyword(query); keyword {
+	case "CREATE", "ALTER", "DROP":
+		return true
+	}
+	return false
+}
+
+// statement returns the statement of query and args, which are either
+// named or positional.
+func statement(query string, args []sqldriver.NamedValue) (spanner.Statement, error) {
+	var (
+		named      = make(map[string]interface{})
+		positional []interface{}
+	)
+	for _, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = arg.Value
+		} else {
+			positional = append(positional, arg.Value)
+		}
+	}
+	if len(named) > 0 && len(positional) > 0 {
+		return spanner.Statement{}, errors.New("spansqlx: named and positional arguments cannot be mixed")
+	}
+
+	query, n, err := internal.RewritePositional(query)
+	if err != nil {
+		return spanner.Statement{}, err
+	}
+	if n > 0 {
+		if len(positional) != n {
+			return spanner.Statement{}, fmt.Errorf("spansqlx: query has %d placeholders but %d arguments are provided", n, len(args))
+		}
+		for i, v := range positional {
+			named[fmt.Sprintf("p%d", i+1)] = v
+		}
+	} else if len(positional) > 0 {
+		return internal.PrepareStmtAll(query, positional...)
+	}
+
+	// maps are bound without a mapper.
+	return internal.PrepareStmtAny(nil, query, named)
+}
+
+// tx is a transaction of a connection.
+type tx struct {
+	conn *conn
+	rw   *spanner.ReadWriteStmtBasedTransaction
+	ro   *spanner.ReadOnlyTransaction
+}
+
+func (t *tx) Commit() error {
+	t.conn.tx = nil
+	if t.ro != nil {
+		t.ro.Close()
+		return nil
+	}
+	_, err := t.rw.Commit(context.Background())
+	return err
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	if t.ro != nil {
+		t.ro.Close()
+		return nil
+	}
+	t.rw.Rollback(context.Background())
+	return nil
+}
+
+// stmt is a statement prepared by a connection.
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var (
+	_ sqldriver.StmtExecContext  = (*stmt)(nil)
+	_ sqldriver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput is unknown, named parameters may be bound more than once.
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []sqldriver.Value) []sqldriver.NamedValue {
+	nv := make([]sqldriver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = sqldriver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+// result is the result of a statement.
+type result struct {
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, ErrLastInsertID
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
@@ -0,0 +1,368 @@
+package driver_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/driver"
+	"github.com/reiot101/spansqlx/internal/spantest"
+	"google.golang.org/api/option"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// schema are the tables of the test databases.
+const schema = `CREATE TABLE Singers (
+	SingerID INT64 NOT NULL,
+	Name STRING(1024),
+	Score FLOAT64,
+	Active BOOL,
+	Photo BYTES(MAX),
+	Born DATE,
+	UpdatedAt TIMESTAMP,
+) PRIMARY KEY (SingerID)`
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn := spantest.NewConn(t, schema)
+	client, err := spanner.NewClient(context.Background(), spantest.Database, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	insertSingers(t, client)
+
+	db := sql.OpenDB(driver.NewConnector(spansqlx.NewDb(context.Background(), client), option.WithGRPCConn(conn)))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newEmulatorDB returns a sql.DB of a new database of the emulator set by
+// SPANNER_EMULATOR_HOST, in the instance SPANNER_INSTANCE, created if it
+// does not exist.
+func newEmulatorDB(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+
+	instanceName := os.Getenv("SPANNER_INSTANCE")
+	if instanceName == "" {
+		instanceName = "projects/spansqlx/instances/test"
+	}
+	project := instanceName[:strings.Index(instanceName, "/instances/")]
+
+	instanceAdmin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer instanceAdmin.Close()
+	iop, err := instanceAdmin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     project,
+		InstanceId: instanceName[strings.LastIndex(instanceName, "/")+1:],
+		Instance: &instancepb.Instance{
+			Config:      project + "/instanceConfigs/emulator-config",
+			DisplayName: "spansqlx",
+			NodeCount:   1,
+		},
+	})
+	if err == nil {
+		_, err = iop.Wait(ctx)
+	}
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		t.Fatal(err)
+	}
+
+	databaseAdmin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer databaseAdmin.Close()
+	dop, err := databaseAdmin.CreateDatabase(ctx, &databasepb.CreateDatabaseRequest{
+		Parent:          instanceName,
+		CreateStatement: fmt.Sprintf("CREATE DATABASE `test-%d`", time.Now().UnixNano()),
+		ExtraStatements: []string{schema},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := dop.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin, err := database.NewDatabaseAdminClient(context.Background())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer admin.Close()
+		if err := admin.DropDatabase(context.Background(), &databasepb.DropDatabaseRequest{Database: created.Name}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	client, err := spanner.NewClient(ctx, created.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	insertSingers(t, client)
+
+	db, err := sql.Open(driver.DriverName, created.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// insertSingers inserts the singers 1 and 2 read by the tests.
+func insertSingers(t *testing.T, client *spanner.Client) {
+	t.Helper()
+
+	columns := []string{"SingerID", "Name", "Score", "Active", "Photo", "Born", "UpdatedAt"}
+	if _, err := client.Apply(context.Background(), []*spanner.Mutation{
+		spanner.Insert("Singers", columns, []interface{}{1, "Marc", 1.5, true, []byte("m"),
+			civil.Date{Year: 1970, Month: 1, Day: 2}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}),
+		spanner.Insert("Singers", []string{"SingerID", "Name"}, []interface{}{2, "Catalina"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	testQuery(t, newTestDB(t))
+}
+
+func testQuery(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+
+	var (
+		name      string
+		score     float64
+		active    bool
+		photo     []byte
+		born      time.Time
+		updatedAt time.Time
+	)
+	if err := db.QueryRowContext(ctx, `SELECT Name, Score, Active, Photo, Born, UpdatedAt FROM Singers WHERE SingerID = ?`, 1).
+		Scan(&name, &score, &active, &photo, &born, &updatedAt); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Marc" || score != 1.5 || !active || string(photo) != "m" ||
+		!born.Equal(time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)) || !updatedAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("got %v %v %v %q %v %v", name, score, active, photo, born, updatedAt)
+	}
+
+	// NULL columns
+	var nullScore sql.NullFloat64
+	var nullName sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT Score, Name FROM Singers WHERE SingerID = @id`, sql.Named("id", 2)).
+		Scan(&nullScore, &nullName); err != nil {
+		t.Fatal(err)
+	}
+	if nullScore.Valid || nullName.String != "Catalina" {
+		t.Fatalf("got %v %v", nullScore, nullName)
+	}
+
+	// positional args bound to named parameters in order
+	var n int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Singers WHERE SingerID >= @min AND SingerID <= @max`, 1, 2).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("got %d singers", n)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT SingerID, Name FROM Singers ORDER BY SingerID`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, err := rows.Columns()
+	if err != nil || len(columns) != 2 || columns[1] != "Name" {
+		t.Fatalf("got columns %v, error %v", columns, err)
+	}
+	var names []string
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil || len(names) != 2 || names[1] != "Catalina" {
+		t.Fatalf("got names %v, error %v", names, err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.QueryRowContext(ctx, `SELECT Name FROM Singers WHERE SingerID = ?`, 3).Scan(&name); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got error %v, want sql.ErrNoRows", err)
+	}
+	if _, err := db.QueryContext(ctx, `SELECT * FROM Missing`); err == nil {
+		t.Fatal("expected query error")
+	}
+	if _, err := db.QueryContext(ctx, `SELECT * FROM Singers WHERE SingerID = @id AND Name = ?`, sql.Named("id", 1), "Marc"); err == nil {
+		t.Fatal("expected mixed arguments error")
+	}
+}
+
+func TestExec(t *testing.T) {
+	testExec(t, newTestDB(t))
+}
+
+func testExec(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+
+	res, err := db.ExecContext(ctx, `UPDATE Singers SET Name = @name WHERE SingerID = @id`, sql.Named("name", "Marco"), sql.Named("id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		t.Fatalf("got %d rows affected, error %v", n, err)
+	}
+	if _, err := res.LastInsertId(); !errors.Is(err, driver.ErrLastInsertID) {
+		t.Fatalf("got error %v", err)
+	}
+
+	stmt, err := db.PrepareContext(ctx, `UPDATE Singers SET Score = ? WHERE SingerID = ?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for _, id := range []int64{1, 2} {
+		if _, err := stmt.ExecContext(ctx, 2.5, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Singers WHERE Score = 2.5 AND Name IN ('Marco', 'Catalina')`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("got %d updated singers", n)
+	}
+}
+
+func TestTx(t *testing.T) {
+	testTx(t, newTestDB(t))
+}
+
+func testTx(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE Singers SET Name = 'Tx' WHERE SingerID = 1`); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := tx.QueryRowContext(ctx, `SELECT Name FROM Singers WHERE SingerID = 1`).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT Name FROM Singers WHERE SingerID = 1`).Scan(&name); err != nil || name != "Tx" {
+		t.Fatalf("got name %q, error %v", name, err)
+	}
+
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.QueryRowContext(ctx, `SELECT Name FROM Singers WHERE SingerID = 2`).Scan(&name); err != nil || name != "Catalina" {
+		t.Fatalf("got name %q, error %v", name, err)
+	}
+	if _, err := ro.ExecContext(ctx, `UPDATE Singers SET Name = 'RO' WHERE SingerID = 2`); !errors.Is(err, driver.ErrReadOnlyTx) {
+		t.Fatalf("got error %v, want ErrReadOnlyTx", err)
+	}
+	if err := ro.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted}); err == nil {
+		t.Fatal("expected unsupported isolation level error")
+	}
+}
+
+func TestDDL(t *testing.T) {
+	testDDL(t, newTestDB(t))
+}
+
+func testDDL(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE Albums (
+	SingerID INT64 NOT NULL,
+	AlbumID INT64 NOT NULL,
+	Title STRING(MAX),
+) PRIMARY KEY (SingerID, AlbumID)`); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Albums`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("got %d albums, error %v", n, err)
+	}
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE Albums (AlbumID INT64) PRIMARY KEY (AlbumID)`); err == nil {
+		t.Fatal("expected existing table error")
+	}
+	if _, err := db.ExecContext(ctx, `DROP TABLE Albums`, 1); err == nil {
+		t.Fatal("expected arguments error")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DROP TABLE Albums`); !errors.Is(err, driver.ErrDDLInTx) {
+		t.Fatalf("got error %v, want ErrDDLInTx", err)
+	}
+}
+
+// TestEmulator runs the tests against a database of its own of the
+// emulator set by SPANNER_EMULATOR_HOST, opened by sql.Open.
+func TestEmulator(t *testing.T) {
+	if os.Getenv("SPANNER_EMULATOR_HOST") == "" {
+		t.Skip("SPANNER_EMULATOR_HOST is not set")
+	}
+
+	for name, test := range map[string]func(*testing.T, *sql.DB){
+		"Query": testQuery,
+		"Exec":  testExec,
+		"Tx":    testTx,
+		"DDL":   testDDL,
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			test(t, newEmulatorDB(t))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"io"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// rows are the rows of a query, read from spanner as they are scanned.
+type rows struct {
+	rows *spansqlx.Rows
+	// next is set if the current row of rows was not returned yet.
+	next bool
+}
+
+// newRows reads the first row of r, so that the query fails early and its
+// columns are known.
+func newRows(r *spansqlx.Rows) (*rows, error) {
+	next := r.Next()
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return &rows{rows: r, next: next}, nil
+}
+
+func (r *rows) Columns() []string {
+	return r.rows.Columns()
+}
+
+func (r *rows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *rows) Next(dest []sqldriver.Value) error {
+	if r.next {
+		r.next = false
+	} else if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	row := r.rows.Row()
+	for i := range dest {
+		var col spanner.GenericColumnValue
+		if err := row.Column(i, &col); err != nil {
+			return err
+		}
+		v, err := value(col)
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	return nil
+}
+
+// value returns the driver value of col. NULL is nil, DATE is a time.Time
+// in UTC, NUMERIC a decimal string and JSON the string of the document.
+// ARRAY and STRUCT columns are returned as the spanner.GenericColumnValue,
+// to be scanned into a sql.Scanner or an interface{}.
+func value(col spanner.GenericColumnValue) (sqldriver.Value, error) {
+	switch col.Type.GetCode() {
+	case sppb.TypeCode_BOOL:
+		var v spanner.NullBool
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.Bool, nil
+	case sppb.TypeCode_INT64:
+		var v spanner.NullInt64
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.Int64, nil
+	case sppb.TypeCode_FLOAT64:
+		var v spanner.NullFloat64
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.Float64, nil
+	case sppb.TypeCode_STRING:
+		var v spanner.NullString
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.StringVal, nil
+	case sppb.TypeCode_BYTES:
+		var v []byte
+		if err := col.Decode(&v); err != nil || v == nil {
+			return nil, err
+		}
+		return v, nil
+	case sppb.TypeCode_TIMESTAMP:
+		var v spanner.NullTime
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.Time, nil
+	case sppb.TypeCode_DATE:
+		var v spanner.NullDate
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return v.Date.In(time.UTC), nil
+	case sppb.TypeCode_NUMERIC:
+		var v spanner.NullNumeric
+		if err := col.Decode(&v); err != nil || !v.Valid {
+			return nil, err
+		}
+		return spanner.NumericString(&v.Numeric), nil
+	case sppb.TypeCode_JSON:
+		if _, ok := col.Value.GetKind().(*structpb.Value_NullValue); ok {
+			return nil, nil
+		}
+		return col.Value.GetStringValue(), nil
+	}
+	return col, nil
+}
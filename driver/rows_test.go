@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestValue(t *testing.T) {
+	json := &sppb.Type{Code: sppb.TypeCode_JSON}
+	array := &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}}
+	strct := &sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: []*sppb.StructType_Field{
+		{Name: "Name", Type: &sppb.Type{Code: sppb.TypeCode_STRING}},
+	}}}
+
+	for _, tt := range []struct {
+		name string
+		col  spanner.GenericColumnValue
+		want interface{}
+	}{
+		{"json", spanner.GenericColumnValue{Type: json, Value: structpb.NewStringValue(`{"a":1}`)}, `{"a":1}`},
+		{"null json", spanner.GenericColumnValue{Type: json, Value: structpb.NewNullValue()}, nil},
+	} {
+		got, err := value(tt.col)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %#v, error %v, want %#v", tt.name, got, err, tt.want)
+		}
+	}
+
+	// ARRAY and STRUCT columns are left to the scanner.
+	for _, col := range []spanner.GenericColumnValue{
+		{Type: array, Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("1")}})},
+		{Type: strct, Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("Marc")}})},
+	} {
+		got, err := value(col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gcv, ok := got.(spanner.GenericColumnValue)
+		if !ok || gcv.Type != col.Type {
+			t.Fatalf("got %#v, want the column value of %v", got, col.Type)
+		}
+	}
+
+	var ids []int64
+	gcv, _ := value(spanner.GenericColumnValue{Type: array, Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+		structpb.NewStringValue("1"), structpb.NewStringValue("2"),
+	}})})
+	if err := gcv.(spanner.GenericColumnValue).Decode(&ids); err != nil || len(ids) != 2 || ids[1] != 2 {
+		t.Fatalf("got ids %v, error %v", ids, err)
+	}
+}
@@ -0,0 +1,149 @@
+package spansqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/reiot101/spansqlx/internal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrBadConn = errors.New("spansqlx: bad connection")
+	// ErrNoRows is returned by Get on an empty result set. It matches
+	// sql.ErrNoRows with errors.Is.
+	ErrNoRows error = noRowsError{}
+)
+
+type noRowsError struct{}
+
+func (noRowsError) Error() string { return "spansqlx: no rows" }
+
+func (noRowsError) Is(target error) bool { return target == sql.ErrNoRows }
+
+// Error is the error of an operation of a DB. It wraps the error of spanner,
+// or of an interceptor, and classifies it by gRPC code.
+type Error struct {
+	Op OpKind
+	// SQL of the statement, the first of a batch, with its literals
+	// redacted. Parameter values are never recorded.
+	SQL string
+	// Table written by the statement or the mutations, if known.
+	Table string
+	Code  codes.Code
+	Err   error
+}
+
+func (e *Error) Error() string {
+	if e.Table != "" {
+		return fmt.Sprintf("spansqlx: %s %s: %v", e.Op, e.Table, e.Err)
+	}
+	return fmt.Sprintf("spansqlx: %s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the status of the wrapped error, so that spanner and
+// gRPC classify e by its code, e.g. to retry aborted transactions.
+func (e *Error) GRPCStatus() *status.Status {
+	var se grpcStatus
+	if errors.As(e.Err, &se) {
+		return se.GRPCStatus()
+	}
+	return status.New(e.Code, e.Err.Error())
+}
+
+type grpcStatus interface {
+	GRPCStatus() *status.Status
+}
+
+// wrapError returns err as the *Error of op. Errors which already are an
+// *Error keep the operation which failed first, e.g. a statement failing its
+// transaction, and errors without an operation are returned as is.
+func wrapError(op *Operation, err error) error {
+	if err == nil || op == nil {
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	e = &Error{Op: op.Kind, Code: ErrCode(err), Err: err}
+	if len(op.Statements) > 0 {
+		e.SQL = internal.SanitizeLiterals(op.Statements[0].SQL)
+		e.Table = internal.StatementTable(op.Statements[0].SQL)
+	}
+	return e
+}
+
+// withTable sets the table of err, the *Error of the OpApply of mutations of
+// table, which spanner does not tell.
+func withTable(err error, table string) error {
+	var e *Error
+	if errors.As(err, &e) && e.Op == OpApply && e.Table == "" {
+		e.Table = table
+	}
+	return err
+}
+
+// ErrCode returns the gRPC code of err, codes.OK if err is nil. Context
+// errors are classified as codes.DeadlineExceeded and codes.Canceled, other
+// errors without a status as codes.Unknown.
+func ErrCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	var se grpcStatus
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Code()
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Unknown
+}
+
+// IsNotFound reports whether err is ErrNoRows, or a NotFound error such as
+// an update of a missing row by a mutation.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNoRows) || ErrCode(err) == codes.NotFound
+}
+
+// IsAlreadyExists reports whether err is an AlreadyExists error, such as an
+// insert of an existing row.
+func IsAlreadyExists(err error) bool {
+	return ErrCode(err) == codes.AlreadyExists
+}
+
+// IsAborted reports whether err is an Aborted error, or ErrMaxAttempts.
+// Spanner retries aborted transactions on its own, the error is only
+// returned when they are not retried.
+func IsAborted(err error) bool {
+	return errors.Is(err, ErrMaxAttempts) || ErrCode(err) == codes.Aborted
+}
+
+// IsDeadlineExceeded reports whether err is a DeadlineExceeded error, or the
+// deadline of the context.
+func IsDeadlineExceeded(err error) bool {
+	return ErrCode(err) == codes.DeadlineExceeded
+}
+
+// IsConstraintViolation reports whether err violates a constraint of the
+// schema: a duplicate key is AlreadyExists, a NOT NULL column, a foreign key
+// or a check constraint is FailedPrecondition.
+func IsConstraintViolation(err error) bool {
+	switch ErrCode(err) {
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package spansqlx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrors(t *testing.T) {
+	db, client := newTestDB(t)
+	ctx := context.Background()
+
+	var s Singer
+	err := db.Get(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, 100)
+	if !errors.Is(err, sql.ErrNoRows) || !spansqlx.IsNotFound(err) {
+		t.Fatalf("got error %v, want no rows", err)
+	}
+
+	err = db.Insert(ctx, "Singers", allSingers[0])
+	var e *spansqlx.Error
+	if !errors.As(err, &e) || e.Op != spansqlx.OpApply || e.Table != "Singers" || e.Code != codes.AlreadyExists {
+		t.Fatalf("got error %#v", err)
+	}
+	if !spansqlx.IsAlreadyExists(err) || !spansqlx.IsConstraintViolation(err) || spansqlx.IsAborted(err) {
+		t.Fatalf("misclassified error %v", err)
+	}
+
+	err = db.Delete(ctx, "Missing", spanner.Key{1})
+	if !errors.As(err, &e) || e.Op != spansqlx.OpApply || e.Table != "Missing" {
+		t.Fatalf("got error %#v", err)
+	}
+
+	_, err = db.Exec(ctx, `UPDATE Missing SET FirstName = 'x' WHERE SingerID = @id`, 1)
+	if !errors.As(err, &e) || e.Op != spansqlx.OpExec || e.Table != "Missing" || e.SQL != `UPDATE Missing SET FirstName = ? WHERE SingerID = @id` {
+		t.Fatalf("got error %#v", err)
+	}
+
+	// aborted statements are still retried by spanner once wrapped.
+	aborted := spanner.ToSpannerError(status.Error(codes.Aborted, "aborted"))
+	abortOnce := spansqlx.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+			if op.Kind == spansqlx.OpExec && op.Attempt == 1 {
+				return ctx, aborted
+			}
+			return ctx, nil
+		},
+	}
+	db = spansqlx.NewDb(ctx, client, spansqlx.WithInterceptors(abortOnce))
+	var attempts int
+	if err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		attempts++
+		_, err := db.Exec(ctx, `UPDATE Singers SET LastName = 'Aborted' WHERE SingerID = 1`)
+		return err
+	}); err != nil || attempts != 2 {
+		t.Fatalf("got error %v after %d attempts", err, attempts)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, -time.Second)
+	defer cancel()
+	if _, err := db.Exec(ctx, `UPDATE Singers SET LastName = 'Late' WHERE SingerID = 1`); !spansqlx.IsDeadlineExceeded(err) {
+		t.Fatalf("got error %v, want deadline exceeded", err)
+	}
+}
@@ -15,7 +15,7 @@ func NewTestClient(t *testing.T, reset bool) (*spansqlx.DB, error) {
 	ctx := context.Background()
 
 	if os.Getenv("SPANNER_EMULATOR_HOST") == "" {
-		os.Setenv("SPANNER_EMULATOR_HOST", "localhost:9010")
+		t.Skip("SPANNER_EMULATOR_HOST is not set")
 	}
 
 	database := "projects/sandbox/instances/sandbox/databases/sandbox"
@@ -53,7 +53,7 @@ func Example_usage() {
 	if err := db.TxPipeline(context.Background(), func(ctx context.Context) error {
 		var sqlInsertSingers = `INSERT INTO Singers (SingerId, FirstName, LastName) VALUES(@singer_id, @first_name, @last_name)`
 		for _, singer := range allSingers {
-			if err := db.Exec(ctx, sqlInsertSingers,
+			if _, err := db.Exec(ctx, sqlInsertSingers,
 				singer.SingerID,
 				singer.FirstName,
 				singer.LastName,
@@ -64,7 +64,7 @@ func Example_usage() {
 
 		var sqlInsertAlbums = `INSERT INTO Albums (SingerID, AlbumID, AlbumTitle) VALUES (@SingerID, @AlbumID, @AlbumTitle)`
 		for _, album := range allAlbums {
-			if err := db.NamedExec(ctx, sqlInsertAlbums, album); err != nil {
+			if _, err := db.NamedExec(ctx, sqlInsertAlbums, album); err != nil {
 				return err
 			}
 		}
@@ -85,7 +85,7 @@ func Example_usage() {
 	fmt.Printf("%#v\n", david)
 }
 
-func ExampleSpanner_usage() {
+func ExampleNewDb() {
 	// create spanner client.
 	client, err := spanner.NewClient(context.Background(), database)
 	if err != nil {
@@ -115,13 +115,14 @@ func ExampleSpanner_usage() {
 		}
 
 		// Add an new song for richards singer.
-		return db.Exec(
+		_, err = db.Exec(
 			txCtx,
 			`INSERT INTO Albums (SingerID, AlbumID, AlbumTitle) VALUES (@SingerID, @AlbumID, @AlbumTitle)`,
 			richards.SingerID,
 			3,
 			"New Song",
 		)
+		return err
 	}); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,134 @@
+package spansqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+	"google.golang.org/api/iterator"
+)
+
+// ErrTooManyRows is matched with errors.Is by the *TooManyRowsError of
+// GetOne.
+var ErrTooManyRows = errors.New("spansqlx: too many rows")
+
+// maxCountedRows bounds the rows read by GetOne to count the rows of a
+// result set which has more than one.
+const maxCountedRows = 100
+
+// TooManyRowsError is returned by GetOne when the result set has more than
+// one row.
+type TooManyRowsError struct {
+	// Rows read from the result set, at most 100.
+	Rows int64
+	// More is set if rows were left unread.
+	More bool
+}
+
+func (e *TooManyRowsError) Error() string {
+	if e.More {
+		return fmt.Sprintf("spansqlx: expected one row, got more than %d", e.Rows)
+	}
+	return fmt.Sprintf("spansqlx: expected one row, got %d", e.Rows)
+}
+
+func (e *TooManyRowsError) Is(target error) bool {
+	return target == ErrTooManyRows
+}
+
+// GetOne within a transaction.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
+// Unlike Get, an error is returned if the result set has more than one row,
+// e.g. if a lookup which should be unique is not.
+func (d *DB) GetOne(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
+	args, opts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return err
+	}
+	return d.GetOneX(ctx, dest, stmt, opts...)
+}
+
+// GetOneX within a transaction.
+// Based spanner statement.
+// An error is returned if the result set is empty or has more than one row.
+func (d *DB) GetOneX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return d.get(ctx, dest, stmt, true, opts...)
+}
+
+// GetOptional within a transaction.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
+// found is false, and dest untouched, if the result set is empty.
+func (d *DB) GetOptional(ctx context.Context, dest interface{}, sql string, args ...interface{}) (found bool, err error) {
+	args, opts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return false, err
+	}
+	return d.GetOptionalX(ctx, dest, stmt, opts...)
+}
+
+// GetOptionalX within a transaction.
+// Based spanner statement.
+// found is false, and dest untouched, if the result set is empty.
+func (d *DB) GetOptionalX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) (found bool, err error) {
+	if err := d.get(ctx, dest, stmt, false, opts...); err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// get scans the first row of stmt into dest. If strict is set, the result
+// set must not have more rows.
+func (d *DB) get(ctx context.Context, dest interface{}, stmt spanner.Statement, strict bool, opts ...ReadOption) error {
+	var row *spanner.Row
+
+	err := forEach(ctx, d, func(iter *rowIter) error {
+		v, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row = v
+
+		if !strict {
+			return nil
+		}
+		rows := int64(1)
+		for ; rows <= maxCountedRows; rows++ {
+			if _, err := iter.Next(); err == iterator.Done {
+				break
+			} else if err != nil {
+				return err
+			}
+		}
+		if rows > 1 {
+			if rows > maxCountedRows {
+				return &TooManyRowsError{Rows: maxCountedRows, More: true}
+			}
+			return &TooManyRowsError{Rows: rows}
+		}
+		return nil
+	}, stmt, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	if row == nil {
+		return ErrNoRows
+	}
+
+	return internal.ScanAny(d.opts.mapper, row, dest)
+}
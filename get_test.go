@@ -0,0 +1,44 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+func TestGetOne(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	var s Singer
+	if err := db.GetOne(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, allSingers[0].SingerID); err != nil {
+		t.Fatal(err)
+	}
+	if s != allSingers[0] {
+		t.Fatalf("got %+v, want %+v", s, allSingers[0])
+	}
+
+	err := db.GetOne(ctx, &s, `SELECT * FROM Singers`)
+	var tooMany *spansqlx.TooManyRowsError
+	if !errors.Is(err, spansqlx.ErrTooManyRows) || !errors.As(err, &tooMany) || tooMany.Rows != int64(len(allSingers)) || tooMany.More {
+		t.Fatalf("got error %v, want %d rows", err, len(allSingers))
+	}
+
+	if err := db.GetOne(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, 100); !errors.Is(err, spansqlx.ErrNoRows) {
+		t.Fatalf("got error %v, want ErrNoRows", err)
+	}
+
+	found, err := db.GetOptional(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, 100)
+	if err != nil || found {
+		t.Fatalf("got found %v, error %v", found, err)
+	}
+	found, err = db.GetOptional(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, allSingers[1].SingerID)
+	if err != nil || !found || s != allSingers[1] {
+		t.Fatalf("got %+v, found %v, error %v", s, found, err)
+	}
+	if _, err := db.GetOptional(ctx, &s, `SELECT * FROM Missing`); err == nil {
+		t.Fatal("expected query error")
+	}
+}
@@ -0,0 +1,25 @@
+package spansqlx
+
+import "github.com/reiot101/spansqlx/internal"
+
+// In rewrites every `IN (@p)` or `IN @p` of sql whose argument is a slice
+// into `IN UNNEST(@p)`, so that the slice is bound as an array parameter.
+// An empty slice is an empty array, the predicate is then false rather than
+// a syntax error. Positional arguments are matched to the parameters of sql
+// as with Select, and the returned args follow the returned sql.
+//
+//	sql, args, err := spansqlx.In(`SELECT * FROM Singers WHERE SingerID IN (@ids)`, []int64{1, 2})
+//	err = db.Select(ctx, &singers, sql, args...)
+//
+// Select, Get, Query and the Named variants already apply In to their
+// arguments.
+func In(sql string, args ...interface{}) (string, []interface{}, error) {
+	return internal.RewriteInArgs(sql, false, args...)
+}
+
+// InExpand is like In, but expands non-empty slices into one parameter per
+// element, e.g. `IN (@ids_0, @ids_1)`. Empty slices are bound as arrays as
+// with In.
+func InExpand(sql string, args ...interface{}) (string, []interface{}, error) {
+	return internal.RewriteInArgs(sql, true, args...)
+}
@@ -0,0 +1,41 @@
+package spansqlx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+func TestSelectIn(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	var ids []int64
+	if err := db.Select(context.Background(), &ids,
+		`SELECT SingerID FROM Singers WHERE SingerID IN (@ids) ORDER BY SingerID`, []int64{4, 2, 9}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 4 {
+		t.Fatalf("got %v, want [2 4]", ids)
+	}
+
+	ids = nil
+	if err := db.NamedSelect(context.Background(), &ids,
+		`SELECT SingerID FROM Singers WHERE SingerID IN (@ids)`, map[string]interface{}{"ids": []int64{}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("got %v, want no rows", ids)
+	}
+
+	sql, args, err := spansqlx.InExpand(`SELECT SingerID FROM Singers WHERE SingerID IN (@ids) ORDER BY SingerID`, []int64{5, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Select(context.Background(), &ids, sql, args...); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 5 {
+		t.Fatalf("got %v, want [1 5]", ids)
+	}
+}
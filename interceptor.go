@@ -0,0 +1,227 @@
+package spansqlx
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/iterator"
+)
+
+// OpKind is the kind of an Operation.
+type OpKind string
+
+const (
+	OpQuery            OpKind = "query"
+	OpPartitionedQuery OpKind = "partitioned query"
+	OpExec             OpKind = "exec"
+	OpBatch            OpKind = "batch"
+	OpPartitionedExec  OpKind = "partitioned exec"
+	OpApply            OpKind = "apply"
+	// OpTransaction is a TxPipeline or a ReadOnlyPipeline, the operations of
+	// its callback run within it, or the transaction of its own of an Exec or
+	// an ExecBatch outside of a TxPipeline.
+	OpTransaction OpKind = "transaction"
+)
+
+// Read reports whether operations of kind k return rows.
+func (k OpKind) Read() bool {
+	return k == OpQuery || k == OpPartitionedQuery
+}
+
+// TxMode is the transaction an Operation runs in.
+type TxMode string
+
+const (
+	// TxSingle is a single read, or a statement or mutations committed in a
+	// transaction of their own.
+	TxSingle         TxMode = "single"
+	TxReadOnly       TxMode = "read-only"
+	TxReadWrite      TxMode = "read-write"
+	TxBatchReadOnly  TxMode = "batch read-only"
+	TxPartitionedDML TxMode = "partitioned"
+)
+
+// Operation is a statement, a batch of statements or a set of mutations run
+// by a DB, as seen by the interceptors.
+type Operation struct {
+	Kind OpKind
+	// Statements run by the operation, one but for batches. Interceptors
+	// may rewrite them before the operation runs.
+	Statements []spanner.Statement
+	// Mutations of an OpApply, which interceptors may rewrite as well.
+	Mutations []*spanner.Mutation
+	TxMode    TxMode
+	// TxID identifies the read-write transaction of a TxPipeline, all its
+	// attempts included. It is a random id chosen by the DB, not the spanner
+	// id of the transaction.
+	TxID string
+	// RequestTag set by SetRequestTag.
+	RequestTag string
+	// Attempt of the read-write transaction the operation runs in, starting
+	// at 1 and incremented when spanner aborts and retries it. It is the
+	// number of attempts of an OpTransaction once done.
+	Attempt int
+
+	// Start is the time the operation started, after the Before hooks.
+	Start time.Time
+	// Duration, Rows and Err are set once the operation is done. Rows are
+	// the rows returned by a query, or affected by a statement.
+	Duration time.Duration
+	Rows     int64
+	Err      error
+}
+
+// Interceptor wraps every operation of a DB, e.g. to audit, guard or
+// measure them.
+type Interceptor interface {
+	// Before is called before op runs. It may rewrite the statements or
+	// mutations of op, and return a context for the operation and the next
+	// interceptors. An error short-circuits the operation, which fails with
+	// it.
+	Before(ctx context.Context, op *Operation) (context.Context, error)
+	// After is called once op is done, with the context returned by Before.
+	// It is called in reverse order, only if Before succeeded.
+	After(ctx context.Context, op *Operation)
+}
+
+// InterceptorFuncs is an Interceptor of optional functions.
+type InterceptorFuncs struct {
+	BeforeFunc func(ctx context.Context, op *Operation) (context.Context, error)
+	AfterFunc  func(ctx context.Context, op *Operation)
+}
+
+// Before calls f.BeforeFunc if set.
+func (f InterceptorFuncs) Before(ctx context.Context, op *Operation) (context.Context, error) {
+	if f.BeforeFunc == nil {
+		return ctx, nil
+	}
+	return f.BeforeFunc(ctx, op)
+}
+
+// After calls f.AfterFunc if set.
+func (f InterceptorFuncs) After(ctx context.Context, op *Operation) {
+	if f.AfterFunc != nil {
+		f.AfterFunc(ctx, op)
+	}
+}
+
+// WithInterceptors appends interceptors to the chain of the DB. Their Before
+// hooks are called in order, and their After hooks in reverse order.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *Options) error {
+		o.interceptors = append(o.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// operation is an Operation in progress.
+type operation struct {
+	*Operation
+	// contexts returned by the Before hooks which succeeded.
+	ctxs []context.Context
+}
+
+// startOperation runs the Before hooks of the operation kind of stmts or
+// mutations, run by tx, which is nil unless the operation joins a
+// transaction, or the TxMode of the transaction the operation begins. The
+// returned context is for the operation, which must not run if an error is
+// returned, but must be ended anyway.
+func (d *DB) startOperation(ctx context.Context, kind OpKind, tx interface{}, stmts []spanner.Statement, mutations []*spanner.Mutation) (context.Context, *operation, error) {
+	op := &operation{Operation: &Operation{Kind: kind, Statements: stmts, Mutations: mutations,
+		RequestTag: requestTagContext(ctx), Attempt: attemptContext(ctx)}}
+
+	switch tx := tx.(type) {
+	case TxMode:
+		op.TxMode = tx
+	case *spanner.ReadWriteTransaction:
+		op.TxMode = TxReadWrite
+		op.TxID = txIDContext(ctx)
+	case *spanner.ReadOnlyTransaction:
+		op.TxMode = TxReadOnly
+	case *spanner.BatchReadOnlyTransaction:
+		op.TxMode = TxBatchReadOnly
+	default:
+		op.TxMode = TxSingle
+		if kind == OpPartitionedExec {
+			op.TxMode = TxPartitionedDML
+		}
+	}
+
+	for _, i := range d.opts.interceptors {
+		next, err := i.Before(ctx, op.Operation)
+		if err != nil {
+			op.Start = time.Now()
+			return ctx, op, err
+		}
+		ctx = next
+		op.ctxs = append(op.ctxs, ctx)
+	}
+
+	op.Start = time.Now()
+	return ctx, op, nil
+}
+
+// endOperation runs the After hooks of op, which returned or affected rows,
+// and logs it. It returns err as an *Error of op.
+func (d *DB) endOperation(ctx context.Context, op *operation, rows int64, err error) error {
+	op.Duration = time.Since(op.Start)
+	op.Rows = rows
+	op.Err = err
+
+	for i := len(op.ctxs) - 1; i >= 0; i-- {
+		d.opts.interceptors[i].After(op.ctxs[i], op.Operation)
+	}
+
+	d.logOperation(ctx, op.Operation)
+	return wrapError(op.Operation, err)
+}
+
+// rowIter is a *spanner.RowIterator which counts its rows, and reports them
+// to stopped once stopped. Its errors are errors of op.
+type rowIter struct {
+	iter    *spanner.RowIterator
+	op      *Operation
+	stopped func(rows int64, err error)
+	rows    int64
+	err     error
+	done    bool
+}
+
+// newRowIter returns a rowIter of iter which ends op in ctx, or which fails
+// with err if op was short-circuited.
+func newRowIter(ctx context.Context, d *DB, op *operation, iter *spanner.RowIterator, err error) *rowIter {
+	return &rowIter{iter: iter, op: op.Operation, err: err, stopped: func(rows int64, err error) {
+		d.endOperation(ctx, op, rows, err)
+	}}
+}
+
+// Next returns the next row of the iterator.
+func (it *rowIter) Next() (*spanner.Row, error) {
+	if it.iter == nil {
+		// short-circuited by an interceptor
+		return nil, wrapError(it.op, it.err)
+	}
+
+	row, err := it.iter.Next()
+	switch {
+	case err == nil:
+		it.rows++
+	case err != iterator.Done:
+		it.err = err
+		return nil, wrapError(it.op, err)
+	}
+	return row, err
+}
+
+// Stop the iterator and report its rows, only once.
+func (it *rowIter) Stop() {
+	if it.done {
+		return
+	}
+	it.done = true
+	if it.iter != nil {
+		it.iter.Stop()
+	}
+	it.stopped(it.rows, it.err)
+}
@@ -0,0 +1,114 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterceptors(t *testing.T) {
+	_, client := newTestDB(t)
+	ctx := context.Background()
+
+	var (
+		calls []string
+		ops   []spansqlx.Operation
+	)
+	errDenied := errors.New("denied")
+	guard := spansqlx.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+			calls = append(calls, "guard before")
+			for i, s := range op.Statements {
+				if strings.HasPrefix(s.SQL, "DELETE") {
+					return ctx, errDenied
+				}
+				// restrict reads to the singer 2
+				op.Statements[i].SQL = strings.Replace(s.SQL, "WHERE true", "WHERE SingerID = 2", 1)
+			}
+			return ctx, nil
+		},
+		AfterFunc: func(ctx context.Context, op *spansqlx.Operation) {
+			calls = append(calls, "guard after")
+		},
+	}
+	audit := spansqlx.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+			calls = append(calls, "audit before")
+			return ctx, nil
+		},
+		AfterFunc: func(ctx context.Context, op *spansqlx.Operation) {
+			calls = append(calls, "audit after")
+			ops = append(ops, *op)
+		},
+	}
+	db := spansqlx.NewDb(ctx, client, spansqlx.WithInterceptors(guard, audit))
+
+	var albums []Album
+	if err := db.Select(ctx, &albums, `SELECT * FROM Albums WHERE true`); err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != 3 {
+		t.Fatalf("got %d albums, want the 3 of singer 2", len(albums))
+	}
+	if want := "guard before,audit before,audit after,guard after"; strings.Join(calls, ",") != want {
+		t.Fatalf("got calls %v, want %s", calls, want)
+	}
+	if op := ops[0]; op.Kind != spansqlx.OpQuery || op.TxMode != spansqlx.TxSingle || op.Rows != 3 || op.Err != nil {
+		t.Fatalf("got operation %+v", op)
+	}
+
+	// short-circuited before the audit, the transaction of the statement is
+	// audited.
+	calls, ops = nil, nil
+	if _, err := db.Exec(ctx, `DELETE FROM Albums WHERE true`); !errors.Is(err, errDenied) {
+		t.Fatalf("got error %v, want errDenied", err)
+	}
+	if want := "guard before,audit before,guard before,audit after,guard after"; strings.Join(calls, ",") != want {
+		t.Fatalf("got calls %v, want %s", calls, want)
+	}
+	var n int64
+	if err := db.Get(ctx, &n, `SELECT COUNT(*) FROM Albums`); err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(allAlbums)) {
+		t.Fatalf("got %d albums, want %d", n, len(allAlbums))
+	}
+
+	ops = nil
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		return db.Insert(ctx, "Albums", Album{SingerID: 3, AlbumID: 1, AlbumTitle: "Audited"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := ops[0]; op.Kind != spansqlx.OpApply || op.TxMode != spansqlx.TxReadWrite || op.TxID == "" || len(op.Mutations) != 1 {
+		t.Fatalf("got operation %+v", op)
+	}
+	txID := ops[0].TxID
+
+	// every attempt of a pipeline has the id of the pipeline.
+	ops = nil
+	var attempts int
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		attempts++
+		if err := db.Insert(ctx, "Albums", Album{SingerID: 3, AlbumID: 2, AlbumTitle: "Retried"}); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return spanner.ToSpannerError(status.Error(codes.Aborted, "aborted"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 || ops[0].TxID == txID || ops[0].TxID != ops[1].TxID || ops[1].TxID != ops[2].TxID {
+		t.Fatalf("got operations %+v", ops)
+	}
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/reflectx"
+)
+
+// CommitTimestampOption is the tag option of a TIMESTAMP field written with
+// the commit timestamp, e.g. `db:"UpdatedAt,commit_timestamp"`.
+const CommitTimestampOption = "commit_timestamp"
+
+// isCommitTimestampField reports whether the field fi is tagged with the
+// commit timestamp option.
+func isCommitTimestampField(fi *reflectx.FieldInfo) bool {
+	_, ok := fi.Options[CommitTimestampOption]
+	return ok
+}
+
+// IsCommitTimestamp reports whether v is the spanner.CommitTimestamp
+// placeholder. The placeholder is told apart from the Unix epoch by its
+// location.
+func IsCommitTimestamp(v interface{}) bool {
+	var t time.Time
+	switch v := v.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return false
+		}
+		t = *v
+	case spanner.NullTime:
+		if !v.Valid {
+			return false
+		}
+		t = v.Time
+	default:
+		return false
+	}
+	return t.Equal(spanner.CommitTimestamp) && t.Location() == spanner.CommitTimestamp.Location()
+}
+
+// writesCommitTimestamp reports whether sql is an INSERT or UPDATE
+// statement, which may write PENDING_COMMIT_TIMESTAMP(). Queries cannot.
+func writesCommitTimestamp(sql string) bool {
+	keyword, err := StatementKeyword(sql)
+	return err == nil && (keyword == "INSERT" || keyword == "UPDATE")
+}
+
+// RewriteCommitTimestamp replaces every parameter of the INSERT or UPDATE
+// statement sql bound to spanner.CommitTimestamp with
+// PENDING_COMMIT_TIMESTAMP(), which DML writes as the commit timestamp of
+// its transaction. The returned values hold the parameters of the rewritten
+// statement. Other statements are returned as is.
+func RewriteCommitTimestamp(sql string, values map[string]interface{}) (string, map[string]interface{}, error) {
+	if !writesCommitTimestamp(sql) {
+		return sql, values, nil
+	}
+
+	params, err := ParseParams(sql)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b      strings.Builder
+		last   int
+		out    = values
+		cloned bool
+	)
+	for _, p := range params {
+		if !IsCommitTimestamp(values[p.Name]) {
+			continue
+		}
+
+		if !cloned {
+			out = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+			cloned = true
+		}
+
+		b.WriteString(sql[last:p.Offset])
+		last = p.Offset + 1 + len(p.Name)
+		b.WriteString("PENDING_COMMIT_TIMESTAMP()")
+		delete(out, p.Name)
+	}
+
+	if !cloned {
+		return sql, values, nil
+	}
+	b.WriteString(sql[last:])
+
+	return b.String(), out, nil
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/reflectx"
+)
+
+func TestPrepareStmtCommitTimestamp(t *testing.T) {
+	type event struct {
+		ID        int64
+		UpdatedAt time.Time `db:"UpdatedAt,commit_timestamp"`
+	}
+
+	m := reflectx.NewMapper("spanner", "db")
+	stmt, err := PrepareStmtAny(m, `UPDATE Events SET UpdatedAt=@UpdatedAt WHERE ID=@ID`, event{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE Events SET UpdatedAt=PENDING_COMMIT_TIMESTAMP() WHERE ID=@ID`; stmt.SQL != want {
+		t.Errorf("got sql %q, want %q", stmt.SQL, want)
+	}
+	if want := map[string]interface{}{"ID": int64(1)}; !reflect.DeepEqual(stmt.Params, want) {
+		t.Errorf("got params %v, want %v", stmt.Params, want)
+	}
+
+	stmt, err = PrepareStmtAll(`UPDATE Events SET UpdatedAt=@at, CreatedAt=@epoch WHERE ID=@id`,
+		spanner.CommitTimestamp, time.Unix(0, 0), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE Events SET UpdatedAt=PENDING_COMMIT_TIMESTAMP(), CreatedAt=@epoch WHERE ID=@id`; stmt.SQL != want {
+		t.Errorf("got sql %q, want %q", stmt.SQL, want)
+	}
+	if want := map[string]interface{}{"epoch": time.Unix(0, 0), "id": 1}; !reflect.DeepEqual(stmt.Params, want) {
+		t.Errorf("got params %v, want %v", stmt.Params, want)
+	}
+
+	// queries filter on the values of tagged fields.
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	stmt, err = PrepareStmtAny(m, `SELECT * FROM Events WHERE UpdatedAt > @UpdatedAt`, event{UpdatedAt: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `SELECT * FROM Events WHERE UpdatedAt > @UpdatedAt`; stmt.SQL != want {
+		t.Errorf("got sql %q, want %q", stmt.SQL, want)
+	}
+	if want := map[string]interface{}{"UpdatedAt": at}; !reflect.DeepEqual(stmt.Params, want) {
+		t.Errorf("got params %v, want %v", stmt.Params, want)
+	}
+
+	stmt, err = PrepareStmtAll(`DELETE FROM Events WHERE UpdatedAt < @at`, spanner.CommitTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `DELETE FROM Events WHERE UpdatedAt < @at`; stmt.SQL != want {
+		t.Errorf("got sql %q, want %q", stmt.SQL, want)
+	}
+
+	ms, err := PrepareMutations(m, spanner.InsertOrUpdate, "Events", &event{ID: 1, UpdatedAt: time.Now()}, ColumnFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := spanner.InsertOrUpdate("Events", []string{"ID", "UpdatedAt"}, []interface{}{int64(1), spanner.CommitTimestamp}); !reflect.DeepEqual(ms[0].Mutation, want) {
+		t.Errorf("got mutation %v, want %v", ms[0].Mutation, want)
+	}
+}
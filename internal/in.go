@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// inList is the span of an `IN (@p)` or `IN @p` operand in a statement.
+type inList struct {
+	Param
+	// start and end offsets of the operand, parentheses included.
+	start, end int
+}
+
+// inLists returns the IN operands of sql which consist of a single parameter.
+func inLists(sql string) ([]inList, error) {
+	params, err := ParseParams(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []inList
+	for _, p := range params {
+		start, end := p.Offset, p.Offset+1+len(p.Name)
+
+		// optional parentheses around the parameter
+		before := skipSpaceBackward(sql, start)
+		after := skipSpaceForward(sql, end)
+		if before > 0 && sql[before-1] == '(' {
+			if after >= len(sql) || sql[after] != ')' {
+				continue
+			}
+			start, end = before-1, after+1
+			before = skipSpaceBackward(sql, start)
+		}
+
+		// preceded by the IN keyword
+		if before < 2 || !strings.EqualFold(sql[before-2:before], "IN") {
+			continue
+		}
+		if before > 2 && isIdentPart(sql[before-3]) {
+			continue
+		}
+
+		lists = append(lists, inList{Param: p, start: start, end: end})
+	}
+
+	return lists, nil
+}
+
+// RewriteIn rewrites every `IN (@p)` or `IN @p` of sql whose value is a slice
+// into `IN UNNEST(@p)`, binding the slice as an array parameter. If expand is
+// set, non-empty slices are instead expanded into `IN (@p_0, @p_1, ...)`
+// with one parameter per element. Empty slices are always bound as arrays, so
+// that the predicate is false (and NOT IN is true) rather than a syntax error.
+// The returned values hold the parameters of the rewritten statement.
+func RewriteIn(sql string, values map[string]interface{}, expand bool) (string, map[string]interface{}, error) {
+	lists, err := inLists(sql)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b       strings.Builder
+		last    int
+		out     = values
+		cloned  bool
+		taken   = make(map[string]bool, len(values))
+		written = make(map[string][]string)
+	)
+	for name := range values {
+		taken[name] = true
+	}
+
+	for _, l := range lists {
+		v := reflect.ValueOf(values[l.Name])
+		if !isInSlice(v) {
+			continue
+		}
+
+		if !cloned {
+			out = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+			cloned = true
+		}
+
+		b.WriteString(sql[last:l.start])
+		last = l.end
+
+		if !expand || v.Len() == 0 {
+			fmt.Fprintf(&b, "UNNEST(@%s)", l.Name)
+			continue
+		}
+
+		names, ok := written[l.Name]
+		if !ok {
+			for i := 0; i < v.Len(); i++ {
+				name := fmt.Sprintf("%s_%d", l.Name, i)
+				for taken[name] {
+					name += "_"
+				}
+				taken[name] = true
+				names = append(names, "@"+name)
+				out[name] = v.Index(i).Interface()
+			}
+			written[l.Name] = names
+		}
+		fmt.Fprintf(&b, "(%s)", strings.Join(names, ", "))
+	}
+
+	if !cloned {
+		return sql, values, nil
+	}
+	b.WriteString(sql[last:])
+	sql = b.String()
+
+	// drop the slices which are no longer referenced.
+	names, err := NamedValueParamNames(sql, -1)
+	if err != nil {
+		return "", nil, err
+	}
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		used[name] = true
+	}
+	for name := range out {
+		if !used[name] {
+			if _, ok := values[name]; ok {
+				delete(out, name)
+			}
+		}
+	}
+
+	return sql, out, nil
+}
+
+// RewriteInArgs is RewriteIn for positional args, which are returned in the
+// order of the parameters of the rewritten statement.
+func RewriteInArgs(sql string, expand bool, args ...interface{}) (string, []interface{}, error) {
+	names, err := NamedValueParamNames(sql, len(args))
+	if err != nil {
+		return "", nil, err
+	}
+
+	values := make(map[string]interface{}, len(names))
+	for i := range names {
+		values[names[i]] = args[i]
+	}
+
+	sql, values, err = RewriteIn(sql, values, expand)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if names, err = NamedValueParamNames(sql, -1); err != nil {
+		return "", nil, err
+	}
+	args = make([]interface{}, len(names))
+	for i := range names {
+		args[i] = values[names[i]]
+	}
+
+	return sql, args, nil
+}
+
+// isInSlice reports whether v is a list of values for an IN operand. Byte
+// slices are BYTES values, not lists.
+func isInSlice(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+func skipSpaceBackward(sql string, i int) int {
+	for i > 0 && isSpace(sql[i-1]) {
+		i--
+	}
+	return i
+}
+
+func skipSpaceForward(sql string, i int) int {
+	for i < len(sql) && isSpace(sql[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewriteInArgs(t *testing.T) {
+	for _, tt := range []struct {
+		sql      string
+		expand   bool
+		args     []interface{}
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			sql:      `SELECT * FROM t WHERE id IN (@ids) AND x=@x`,
+			args:     []interface{}{[]int64{1, 2}, "x"},
+			wantSQL:  `SELECT * FROM t WHERE id IN UNNEST(@ids) AND x=@x`,
+			wantArgs: []interface{}{[]int64{1, 2}, "x"},
+		},
+		{
+			sql:      `SELECT * FROM t WHERE id in @ids OR id NOT IN ( @ids )`,
+			args:     []interface{}{[]string{}},
+			wantSQL:  `SELECT * FROM t WHERE id in UNNEST(@ids) OR id NOT IN UNNEST(@ids)`,
+			wantArgs: []interface{}{[]string{}},
+		},
+		{
+			sql:      `SELECT * FROM t WHERE x=@x AND id IN (@ids) AND y=@y`,
+			expand:   true,
+			args:     []interface{}{1, []int64{7, 8}, 2},
+			wantSQL:  `SELECT * FROM t WHERE x=@x AND id IN (@ids_0, @ids_1) AND y=@y`,
+			wantArgs: []interface{}{1, int64(7), int64(8), 2},
+		},
+		{
+			sql:      `SELECT * FROM t WHERE id IN (@ids) AND ARRAY_LENGTH(@ids) > 0`,
+			expand:   true,
+			args:     []interface{}{[]int64{7}},
+			wantSQL:  `SELECT * FROM t WHERE id IN (@ids_0) AND ARRAY_LENGTH(@ids) > 0`,
+			wantArgs: []interface{}{int64(7), []int64{7}},
+		},
+		{
+			sql:      `SELECT * FROM t WHERE id IN (@ids)`,
+			expand:   true,
+			args:     []interface{}{[]int64(nil)},
+			wantSQL:  `SELECT * FROM t WHERE id IN UNNEST(@ids)`,
+			wantArgs: []interface{}{[]int64(nil)},
+		},
+		{
+			// not an IN operand, or not a slice
+			sql:      `SELECT * FROM t WHERE id IN UNNEST(@ids) AND b IN (@b) AND LOGIN=@l AND c IN (@c, 1)`,
+			args:     []interface{}{[]int64{1}, []byte("b"), []int64{2}, 3},
+			wantSQL:  `SELECT * FROM t WHERE id IN UNNEST(@ids) AND b IN (@b) AND LOGIN=@l AND c IN (@c, 1)`,
+			wantArgs: []interface{}{[]int64{1}, []byte("b"), []int64{2}, 3},
+		},
+	} {
+		sql, args, err := RewriteInArgs(tt.sql, tt.expand, tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sql, err)
+			continue
+		}
+		if sql != tt.wantSQL {
+			t.Errorf("got sql %s, want %s", sql, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%s: got args %#v, want %#v", tt.sql, args, tt.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/reflectx"
+)
+
+// MutationOp builds a mutation of the given columns and values, such as
+// spanner.Insert or spanner.Update.
+type MutationOp func(table string, columns []string, values []interface{}) *spanner.Mutation
+
+// ColumnFilter selects the columns of a mutation. If Include is set only
+// those columns are written, Exclude columns are never written.
+type ColumnFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// allows reports whether the column name passes the filter.
+func (f ColumnFilter) allows(name string) bool {
+	for _, c := range f.Exclude {
+		if strings.EqualFold(c, name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, c := range f.Include {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mutation is a spanner.Mutation with the table, columns and values it
+// writes, which spanner does not export.
+type Mutation struct {
+	*spanner.Mutation
+	Table   string
+	Columns []string
+	Values  []interface{}
+}
+
+// PrepareMutations builds one mutation of op per struct or map in arg, which
+// may be a struct, a map with string keys, or a slice of either. Struct
+// fields are named by m, the fields of nested structs which are not embedded
+// must be excluded by filter.
+func PrepareMutations(m *reflectx.Mapper, op MutationOp, table string, arg interface{}, filter ColumnFilter) ([]Mutation, error) {
+	v := reflect.Indirect(reflect.ValueOf(arg))
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		ms := make([]Mutation, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			mu, err := prepareMutation(m, op, table, reflect.Indirect(v.Index(i)), filter)
+			if err != nil {
+				return nil, err
+			}
+			ms = append(ms, mu)
+		}
+		return ms, nil
+	}
+
+	mu, err := prepareMutation(m, op, table, v, filter)
+	if err != nil {
+		return nil, err
+	}
+	return []Mutation{mu}, nil
+}
+
+// SpannerMutations returns the spanner mutations of ms.
+func SpannerMutations(ms []Mutation) []*spanner.Mutation {
+	sms := make([]*spanner.Mutation, len(ms))
+	for i := range ms {
+		sms[i] = ms[i].Mutation
+	}
+	return sms
+}
+
+func prepareMutation(m *reflectx.Mapper, op MutationOp, table string, v reflect.Value, filter ColumnFilter) (Mutation, error) {
+	var (
+		columns []string
+		values  []interface{}
+	)
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return Mutation{}, fmt.Errorf("spansqlx: unsupported map key type %s", v.Type().Key())
+		}
+		for _, key := range v.MapKeys() {
+			columns = append(columns, key.String())
+		}
+		// map order is random, keep mutations reproducible
+		sort.Strings(columns)
+		for i := 0; i < len(columns); {
+			if !filter.allows(columns[i]) {
+				columns = append(columns[:i], columns[i+1:]...)
+				continue
+			}
+			values = append(values, v.MapIndex(reflect.ValueOf(columns[i]).Convert(v.Type().Key())).Interface())
+			i++
+		}
+	case reflect.Struct:
+		for _, fi := range m.TypeMap(v.Type()).Index {
+			if !filter.allows(fi.Name) {
+				continue
+			}
+			if strings.Contains(fi.Name, ".") {
+				// the fields of nested structs are named owner.name, which
+				// is not a column.
+				return Mutation{}, fmt.Errorf("spansqlx: nested struct field %s of %s has no column, embed its struct or exclude %s", fi.Path, v.Type(), fi.Name)
+			}
+			columns = append(columns, fi.Name)
+			if isCommitTimestampField(fi) {
+				values = append(values, spanner.CommitTimestamp)
+			} else {
+				values = append(values, fieldValue(v, fi))
+			}
+		}
+	default:
+		return Mutation{}, fmt.Errorf("spansqlx: unsupported mutation argument type %s", v.Type())
+	}
+
+	for _, c := range filter.Include {
+		if !containsFold(columns, c) {
+			return Mutation{}, fmt.Errorf("spansqlx: column %s not found in %s", c, v.Type())
+		}
+	}
+	if len(columns) == 0 {
+		return Mutation{}, fmt.Errorf("spansqlx: no columns to write in %s", v.Type())
+	}
+
+	return Mutation{Mutation: op(table, columns, values), Table: table, Columns: columns, Values: values}, nil
+}
+
+// fieldValue returns the value of the field fi of the struct v. A nil
+// parent struct is a typed NULL.
+func fieldValue(v reflect.Value, fi *reflectx.FieldInfo) interface{} {
+	f := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+	if !f.IsValid() {
+		t := fi.Field.Type
+		if t.Kind() != reflect.Ptr {
+			t = reflect.PtrTo(t)
+		}
+		f = reflect.Zero(t)
+	}
+	return f.Interface()
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MutationCells returns the number of cells written by a mutation of
+// columns, as counted by the spanner commit limits. The cells are the
+// columns, or one for a delete without columns, plus the columns of every
+// secondary index of indexes which covers a written column.
+func MutationCells(columns []string, indexes [][]string) int {
+	if len(columns) == 0 {
+		// delete, the index entries of the row are removed as well.
+		cells := 1
+		for _, index := range indexes {
+			cells += len(index)
+		}
+		return cells
+	}
+
+	cells := len(columns)
+	for _, index := range indexes {
+		for _, c := range columns {
+			if containsFold(index, c) {
+				cells += len(index)
+				break
+			}
+		}
+	}
+	return cells
+}
+
+// MutationSize returns an estimate of the size in bytes of a mutation of
+// table, columns and values, which are the keys of a delete.
+func MutationSize(table string, columns []string, values interface{}) int {
+	size := len(table) + valueSize(reflect.ValueOf(values), 0)
+	for _, c := range columns {
+		size += len(c)
+	}
+	return size
+}
+
+// valueSize estimates the encoded size of v in bytes. It only relies on the
+// methods which are allowed on unexported fields, such as those of a
+// spanner.KeySet. Pointers held by structs, such as the location of a
+// time.Time, are not followed.
+func valueSize(v reflect.Value, depth int) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Ptr:
+		if v.IsNil() || depth > 0 {
+			return 8
+		}
+		return valueSize(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return valueSize(v.Elem(), depth)
+	case reflect.String:
+		return v.Len()
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Len()
+		}
+		n := 0
+		for i := 0; i < v.Len(); i++ {
+			n += valueSize(v.Index(i), depth)
+		}
+		return n
+	case reflect.Map:
+		n := 0
+		iter := v.MapRange()
+		for iter.Next() {
+			n += valueSize(iter.Key(), depth) + valueSize(iter.Value(), depth)
+		}
+		return n
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < v.NumField(); i++ {
+			n += valueSize(v.Field(i), depth+1)
+		}
+		return n
+	default:
+		return 8
+	}
+}
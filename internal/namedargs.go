@@ -2,23 +2,200 @@ package internal
 
 import (
 	"fmt"
-	"regexp"
+	"strings"
 )
 
-var namedValueParamNameRegex = regexp.MustCompile("@(\\w+)")
+// Param is a query parameter found in a statement.
+type Param struct {
+	// Name of the parameter, without the @.
+	Name string
+	// Offset of the @ in the statement.
+	Offset int
+}
+
+// SyntaxError is returned when a statement cannot be tokenized.
+type SyntaxError struct {
+	Msg    string
+	Line   int
+	Column int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("spansqlx: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
 
 // NamedValueParamNames parsing sql query name values
+// The names are returned once each in order of first occurrence. If n is not
+// -1, an error is returned unless sql has exactly n distinct parameters.
 func NamedValueParamNames(sql string, n int) ([]string, error) {
-	var names []string
+	params, err := ParseParams(sql)
+	if err != nil {
+		return nil, err
+	}
 
-	matches := namedValueParamNameRegex.FindAllStringSubmatch(sql, n)
-	if m := len(matches); n != -1 && m < n {
-		return nil, fmt.Errorf("scansqlx: query has %d placeholders but %d arguments are provided", m, n)
+	var (
+		names []string
+		first []Param
+		seen  = make(map[string]bool, len(params))
+	)
+	for _, p := range params {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			names = append(names, p.Name)
+			first = append(first, p)
+		}
 	}
 
-	for _, m := range matches {
-		names = append(names, m[1])
+	if m := len(names); n != -1 && m != n {
+		if m > n {
+			line, col := position(sql, first[n].Offset)
+			return nil, fmt.Errorf("spansqlx: query has %d placeholders but %d arguments are provided, @%s at line %d, column %d has no argument",
+				m, n, first[n].Name, line, col)
+		}
+		return nil, fmt.Errorf("spansqlx: query has %d placeholders but %d arguments are provided", m, n)
 	}
 
 	return names, nil
 }
+
+// ParseParams returns every query parameter of the GoogleSQL statement sql in
+// order of appearance. String and bytes literals (quoted, triple quoted or
+// raw), quoted identifiers, comments and statement hints such as
+// @{FORCE_INDEX=Idx} are skipped.
+func ParseParams(sql string) ([]Param, error) {
+	var params []Param
+	err := scanTokens(sql, func(t token) bool {
+		if t.kind == tokenParam {
+			params = append(params, Param{Name: sql[t.start+1 : t.end], Offset: t.start})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// RewritePositional replaces the positional ? placeholders of the GoogleSQL
+// statement sql with the parameters @p1, @p2, ... in order, and returns
+// their number. Literals, quoted identifiers, comments and statement hints
+// are left as is.
+func RewritePositional(sql string) (string, int, error) {
+	var (
+		b    strings.Builder
+		last int
+		n    int
+	)
+	err := scanTokens(sql, func(t token) bool {
+		if t.kind == tokenOther && sql[t.start] == '?' {
+			n++
+			b.WriteString(sql[last:t.start])
+			fmt.Fprintf(&b, "@p%d", n)
+			last = t.end
+		}
+		return true
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if n == 0 {
+		return sql, 0, nil
+	}
+	b.WriteString(sql[last:])
+	return b.String(), n, nil
+}
+
+// StatementKeyword returns the first keyword of the GoogleSQL statement sql
+// in upper case, e.g. SELECT or UPDATE. Leading comments and statement hints
+// are skipped.
+func StatementKeyword(sql string) (string, error) {
+	words, err := leadingWords(sql, 1)
+	if err != nil || len(words) == 0 {
+		return "", err
+	}
+	return strings.ToUpper(words[0]), nil
+}
+
+// StatementTable returns the table written by the GoogleSQL DML statement
+// sql, e.g. Singers of `INSERT INTO Singers ...`, or "" for other
+// statements.
+func StatementTable(sql string) string {
+	words, err := leadingWords(sql, 3)
+	if err != nil || len(words) < 2 {
+		return ""
+	}
+
+	switch strings.ToUpper(words[0]) {
+	case "INSERT", "DELETE":
+		// INTO and FROM are optional
+		if kw := strings.ToUpper(words[1]); kw == "INTO" || kw == "FROM" {
+			if len(words) < 3 {
+				return ""
+			}
+			return words[2]
+		}
+		return words[1]
+	case "UPDATE":
+		return words[1]
+	}
+	return ""
+}
+
+// leadingWords returns up to n leading identifiers or keywords of sql,
+// skipping spaces, comments and statement hints. Quoted identifiers are
+// unquoted.
+func leadingWords(sql string, n int) ([]string, error) {
+	var words []string
+	err := scanTokens(sql, func(t token) bool {
+		switch {
+		case t.kind == tokenComment, t.kind == tokenHint, t.kind == tokenOther && isSpace(sql[t.start]):
+		case t.kind == tokenQuotedIdent:
+			words = append(words, sql[t.start+1:t.end-1])
+		case t.kind == tokenIdent:
+			words = append(words, sql[t.start:t.end])
+		default:
+			return false
+		}
+		return len(words) < n
+	})
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// SanitizeLiterals replaces the string, bytes and number literals of the
+// GoogleSQL statement sql with ?, so that it can be recorded without the
+// values written in it. Statements which cannot be tokenized are replaced
+// entirely.
+func SanitizeLiterals(sql string) string {
+	var b strings.Builder
+	err := scanTokens(sql, func(t token) bool {
+		if t.kind == tokenString || t.kind == tokenNumber {
+			b.WriteByte('?')
+		} else {
+			b.WriteString(sql[t.start:t.end])
+		}
+		return true
+	})
+	if err != nil {
+		return "?"
+	}
+	return b.String()
+}
+
+func syntaxError(sql string, offset int, msg string) error {
+	line, col := position(sql, offset)
+	return &SyntaxError{Msg: msg, Line: line, Column: col}
+}
+
+// position returns the 1-based line and column of offset in sql.
+func position(sql string, offset int) (int, int) {
+	line := 1 + strings.Count(sql[:offset], "\n")
+	col := offset + 1
+	if nl := strings.LastIndexByte(sql[:offset], '\n'); nl != -1 {
+		col = offset - nl
+	}
+	return line, col
+}
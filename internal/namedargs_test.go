@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamedValueParamNames(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want []string
+	}{
+		{`SELECT 1`, nil},
+		{`SELECT * FROM t WHERE a=@id OR b=@id AND c=@c`, []string{"id", "c"}},
+		{`SELECT * FROM t WHERE email='foo@bar.com' AND id=@id`, []string{"id"}},
+		{`SELECT * FROM t WHERE s="it's @not" AND x=@x`, []string{"x"}},
+		{`SELECT * FROM t WHERE s='it\'s @not' AND x=@x`, []string{"x"}},
+		{`SELECT * FROM t WHERE s=r'\' @not' AND x=@x`, []string{"x"}},
+		{"SELECT * FROM t WHERE s='''multi\n'@not'\n''' AND x=@x", []string{"x"}},
+		{`SELECT * FROM t WHERE s=b"""@not""" AND x=@x`, []string{"x"}},
+		{"SELECT `col@not` FROM t WHERE x=@x", []string{"x"}},
+		{"SELECT * FROM t -- @not\nWHERE x=@x # @not", []string{"x"}},
+		{"SELECT * FROM t /* @not\n @not */ WHERE x=@x", []string{"x"}},
+		{`SELECT * FROM t@{FORCE_INDEX=Idx} WHERE x=@x`, []string{"x"}},
+		{`@{JOIN_METHOD=HASH_JOIN} SELECT * FROM t WHERE x=@x_1`, []string{"x_1"}},
+		{`SELECT @@statement_timeout, @x`, []string{"x"}},
+	} {
+		got, err := NamedValueParamNames(tt.sql, -1)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sql, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestNamedValueParamNamesErrors(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		n    int
+		want string
+	}{
+		{"SELECT 'foo", -1, "spansqlx: unterminated string at line 1, column 8"},
+		{"SELECT '''foo\n'", -1, "spansqlx: unterminated triple quoted string at line 1, column 8"},
+		{"SELECT 1\n/* foo", -1, "spansqlx: unterminated comment at line 2, column 1"},
+		{"SELECT `foo", -1, "spansqlx: unterminated quoted identifier at line 1, column 8"},
+		{"SELECT * FROM t@{FORCE_INDEX=Idx", -1, "spansqlx: unterminated statement hint at line 1, column 16"},
+		{"SELECT @ 1", -1, "spansqlx: invalid query parameter at line 1, column 8"},
+		{"SELECT @a,\n  @b, @a, @c", 2, "spansqlx: query has 3 placeholders but 2 arguments are provided, @c at line 2, column 11 has no argument"},
+		{"SELECT @a, @a", 2, "spansqlx: query has 1 placeholders but 2 arguments are provided"},
+	} {
+		_, err := NamedValueParamNames(tt.sql, tt.n)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got error %v, want %s", tt.sql, err, tt.want)
+		}
+	}
+}
+
+func TestPrepareStmtAll(t *testing.T) {
+	stmt, err := PrepareStmtAll(`SELECT * FROM t WHERE a=@id OR b=@id AND c=@c`, 1, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"id": 1, "c": "c"}; !reflect.DeepEqual(stmt.Params, want) {
+		t.Fatalf("got params %v, want %v", stmt.Params, want)
+	}
+}
+
+func TestStatementKeyword(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want string
+	}{
+		{`update t SET x=1 WHERE true`, "UPDATE"},
+		{"  -- backfill\n\tDELETE FROM t WHERE true", "DELETE"},
+		{"/* insert */ # insert\nUpdate t SET x=1 WHERE true", "UPDATE"},
+		{`@{PDML_MAX_PARALLELISM=4} UPDATE t SET x=1 WHERE true`, "UPDATE"},
+		{`(SELECT 1)`, ""},
+		{``, ""},
+	} {
+		got, err := StatementKeyword(tt.sql)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sql, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	if _, err := StatementKeyword(`/* UPDATE`); err == nil {
+		t.Error("expected unterminated comment error")
+	}
+}
+
+func TestRewritePositional(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want string
+		n    int
+	}{
+		{`SELECT * FROM t WHERE a=? AND b=?`, `SELECT * FROM t WHERE a=@p1 AND b=@p2`, 2},
+		{"SELECT '?', `?` FROM t -- ?\nWHERE a=?", "SELECT '?', `?` FROM t -- ?\nWHERE a=@p1", 1},
+		{`SELECT * FROM t WHERE a=@a`, `SELECT * FROM t WHERE a=@a`, 0},
+	} {
+		got, n, err := RewritePositional(tt.sql)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sql, err)
+			continue
+		}
+		if got != tt.want || n != tt.n {
+			t.Errorf("%s: got %q (%d), want %q (%d)", tt.sql, got, n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestStatementTable(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want string
+	}{
+		{`INSERT INTO Singers (SingerID) VALUES (1)`, "Singers"},
+		{`insert Singers (SingerID) VALUES (1)`, "Singers"},
+		{"-- cleanup\nDELETE FROM `Order` WHERE true", "Order"},
+		{`DELETE Albums WHERE true`, "Albums"},
+		{`@{PDML_MAX_PARALLELISM=4} UPDATE Singers SET x=1 WHERE true`, "Singers"},
+		{`SELECT * FROM Singers`, ""},
+		{`DELETE FROM`, ""},
+		{`/* UPDATE Singers`, ""},
+	} {
+		if got := StatementTable(tt.sql); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeLiterals(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want string
+	}{
+		{`SELECT * FROM t WHERE a = @a`, `SELECT * FROM t WHERE a = @a`},
+		{`SELECT * FROM t2 WHERE email = 'foo@bar.com' AND n > 42`, `SELECT * FROM t2 WHERE email = ? AND n > ?`},
+		{`SELECT r'\d+', b"""x""", 1.5e-3, .5, 0x1F FROM t`, `SELECT ?, ?, ?, ?, ? FROM t`},
+		{"SELECT `col 1` FROM t@{FORCE_INDEX=Idx} -- 'kept'", "SELECT `col 1` FROM t@{FORCE_INDEX=Idx} -- 'kept'"},
+		{`SELECT @@statement_timeout`, `SELECT @@statement_timeout`},
+		{`SELECT 'unterminated`, `?`},
+	} {
+		if got := SanitizeLiterals(tt.sql); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
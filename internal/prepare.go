@@ -1,10 +1,13 @@
 package internal
 
 import (
-	"log"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/reflectx"
 )
 
 // PrepareStmtAll within sql and args(slice) generate Statement
@@ -20,42 +23,171 @@ func PrepareStmtAll(sql string, args ...interface{}) (spanner.Statement, error)
 		stmt.Params[names[i]] = args[i]
 	}
 
-	log.Println("[spansqlx]", stmt)
+	// slices bound to IN (@p) are read as arrays
+	if stmt.SQL, stmt.Params, err = RewriteIn(stmt.SQL, stmt.Params, false); err != nil {
+		return spanner.Statement{}, err
+	}
+
+	// spanner.CommitTimestamp is written by PENDING_COMMIT_TIMESTAMP()
+	if stmt.SQL, stmt.Params, err = RewriteCommitTimestamp(stmt.SQL, stmt.Params); err != nil {
+		return spanner.Statement{}, err
+	}
 
 	return stmt, nil
 }
 
 // PrepareStmtAny within sql and arg(single) generate Statement
-func PrepareStmtAny(sql string, arg interface{}) (spanner.Statement, error) {
-	parseReflect := func(elem reflect.Value) map[string]interface{} {
-		m := make(map[string]interface{})
-
-		switch elem.Kind() {
-		case reflect.Map:
-			keys := elem.MapKeys()
-			for _, key := range keys {
-				k := key.Convert(elem.Type().Key())
-				m[k.String()] = elem.MapIndex(k)
+// Every parameter of sql is bound from the map key, or from the struct field
+// as named by m, of the same name regardless of case. An error is returned if
+// a parameter has no value or matches names which only differ by case, or if
+// a map value is not used by sql.
+// Parameters of INSERT and UPDATE statements bound to
+// spanner.CommitTimestamp, or to fields tagged with the commit_timestamp
+// option, are replaced with PENDING_COMMIT_TIMESTAMP(). Tagged fields are
+// bound to their value in other statements, e.g. to filter a query.
+func PrepareStmtAny(m *reflectx.Mapper, sql string, arg interface{}) (spanner.Statement, error) {
+	names, err := NamedValueParamNames(sql, -1)
+	if err != nil {
+		return spanner.Statement{}, err
+	}
+
+	stmt := spanner.NewStatement(sql)
+
+	v := reflect.Indirect(reflect.ValueOf(arg))
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return spanner.Statement{}, fmt.Errorf("spansqlx: unsupported map key type %s", v.Type().Key())
+		}
+
+		values := make(map[string]interface{}, v.Len())
+		index := make(paramIndex, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().Interface()
+			index.add(iter.Key().String())
+		}
+
+		used := make(map[string]bool, len(values))
+		for _, name := range names {
+			key, err := index.lookup(name)
+			if err != nil {
+				return spanner.Statement{}, err
 			}
-		case reflect.Struct:
-			t := elem.Type()
-			for i := 0; i < t.NumField(); i++ {
-				m[t.Field(i).Name] = elem.Field(i).Interface()
+			if key == "" {
+				return spanner.Statement{}, fmt.Errorf("spansqlx: missing value for parameter @%s", name)
 			}
+			stmt.Params[name] = values[key]
+			used[key] = true
 		}
 
-		return m
-	}
+		var unused []string
+		for key := range values {
+			if !used[key] {
+				unused = append(unused, key)
+			}
+		}
+		if len(unused) > 0 {
+			sort.Strings(unused)
+			return spanner.Statement{}, fmt.Errorf("spansqlx: unused values for %s", strings.Join(unused, ", "))
+		}
+	case reflect.Struct:
+		sm := m.TypeMap(v.Type())
+		dml := writesCommitTimestamp(sql)
 
-	v := reflect.ValueOf(arg)
+		fields := make(map[string]*reflectx.FieldInfo, len(sm.Index))
+		index := make(paramIndex, len(sm.Index))
+		for _, fi := range sm.Index {
+			fields[ParamName(fi.Name)] = fi
+			index.add(ParamName(fi.Name))
+		}
 
-	stmt := spanner.NewStatement(sql)
+		for _, name := range names {
+			for n, fis := range sm.Ambiguous {
+				if strings.EqualFold(ParamName(n), name) {
+					return spanner.Statement{}, &reflectx.AmbiguousError{Name: n, Fields: fis}
+				}
+			}
 
-	if v.Kind() == reflect.Ptr {
-		stmt.Params = parseReflect(v.Elem())
-	} else {
-		stmt.Params = parseReflect(v)
+			key, err := index.lookup(name)
+			if err != nil {
+				return spanner.Statement{}, err
+			}
+			if key == "" {
+				return spanner.Statement{}, fmt.Errorf("spansqlx: missing value for parameter @%s in %s", name, v.Type())
+			}
+
+			if fi := fields[key]; dml && isCommitTimestampField(fi) {
+				stmt.Params[name] = spanner.CommitTimestamp
+			} else {
+				stmt.Params[name] = fieldValue(v, fi)
+			}
+		}
+	default:
+		return spanner.Statement{}, fmt.Errorf("spansqlx: unsupported named argument type %T", arg)
+	}
+
+	// slices bound to IN (@p) are read as arrays
+	if stmt.SQL, stmt.Params, err = RewriteIn(stmt.SQL, stmt.Params, false); err != nil {
+		return spanner.Statement{}, err
+	}
+
+	// spanner.CommitTimestamp is written by PENDING_COMMIT_TIMESTAMP()
+	if stmt.SQL, stmt.Params, err = RewriteCommitTimestamp(stmt.SQL, stmt.Params); err != nil {
+		return spanner.Statement{}, err
 	}
 
 	return stmt, nil
 }
+
+// paramIndex matches parameters to the names of map keys or struct fields
+// regardless of case, as GoogleSQL identifiers are. It maps the lower case
+// names to the names.
+type paramIndex map[string][]string
+
+func (ix paramIndex) add(name string) {
+	lower := strings.ToLower(name)
+	ix[lower] = append(ix[lower], name)
+}
+
+// lookup returns the name matching the parameter, "" if none does, or an
+// error if several names only differ by case.
+func (ix paramIndex) lookup(param string) (string, error) {
+	switch names := ix[strings.ToLower(param)]; len(names) {
+	case 0:
+		return "", nil
+	case 1:
+		return names[0], nil
+	default:
+		sort.Strings(names)
+		return "", fmt.Errorf("spansqlx: parameter @%s matches %s, which only differ by case", param, strings.Join(names, ", "))
+	}
+}
+
+// ParamName returns the parameter name of a mapped field name. Parameter
+// names are identifiers, so the dots of nested struct fields are replaced,
+// e.g. owner.name is bound to @owner_name.
+func ParamName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// PrepareStmts generates one Statement of sql per element of arg, a slice or
+// an array of structs or maps bound as with PrepareStmtAny.
+func PrepareStmts(m *reflectx.Mapper, sql string, arg interface{}) ([]spanner.Statement, error) {
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("spansqlx: unsupported batch argument type %T", arg)
+	}
+
+	stmts := make([]spanner.Statement, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		stmt, err := PrepareStmtAny(m, sql, v.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("%w (element %d)", err, i)
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts, nil
+}
@@ -2,25 +2,56 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 
 	"cloud.google.com/go/spanner"
 	"github.com/reiot101/spansqlx/reflectx"
+	"google.golang.org/api/iterator"
 )
 
 // ScanAll scans all rows into a destination, which must be a slice of any
 // type. If the destination slice type is a Struct, then Struct will be
 // used on each row.  If the destination is some other kind of base type, then
 // each row must only have one column which can scan into that type.
-func ScanAll(rows []*spanner.Row, dest interface{}) error {
+func ScanAll(m *reflectx.Mapper, rows []*spanner.Row, dest interface{}) error {
+	var i int
+
+	return scanSlice(m, func() (*spanner.Row, error) {
+		if i >= len(rows) {
+			return nil, iterator.Done
+		}
+		i++
+		return rows[i-1], nil
+	}, dest)
+}
+
+// RowIterator is the part of *spanner.RowIterator read by ScanIter.
+type RowIterator interface {
+	Next() (*spanner.Row, error)
+	Stop()
+}
+
+// ScanIter is like ScanAll, but reads the rows from iter one at a time
+// instead of requiring the whole result set up front. The iterator is
+// stopped before ScanIter returns.
+func ScanIter(m *reflectx.Mapper, iter RowIterator, dest interface{}) error {
+	defer iter.Stop()
+
+	return scanSlice(m, iter.Next, dest)
+}
+
+// scanSlice appends every row returned by next to the dest slice until next
+// reports iterator.Done.
+func scanSlice(m *reflectx.Mapper, next func() (*spanner.Row, error), dest interface{}) error {
 	var vp reflect.Value
 
 	value := reflect.ValueOf(dest)
 	if value.Kind() != reflect.Ptr {
-		return errors.New("scansqlx: must pass a pointer, not a value, to Struct destination")
+		return errors.New("spansqlx: must pass a pointer, not a value, to Struct destination")
 	}
 	if value.IsNil() {
-		return errors.New("scansqlx: nil pointer passed to Struct destination")
+		return errors.New("spansqlx: nil pointer passed to Struct destination")
 	}
 
 	direct := reflect.Indirect(value)
@@ -32,14 +63,22 @@ func ScanAll(rows []*spanner.Row, dest interface{}) error {
 	isPtr := slice.Elem().Kind() == reflect.Ptr
 	base := reflectx.Deref(slice.Elem())
 
-	for _, row := range rows {
+	for {
+		row, err := next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
 		// create a new struct type (which returns PtrTo) and indirect it
 		vp = reflect.New(base)
 
-		switch base.Kind() {
-		case reflect.Struct:
+		switch {
+		case !reflectx.IsScannable(base):
 			// scan into the struct field pointers and append to our results
-			err = row.ToStruct(vp.Interface())
+			err = scanStruct(m, row, vp)
 		default:
 			// scan into the columns field pointers and append to our results
 			err = row.Columns(vp.Interface())
@@ -56,27 +95,25 @@ func ScanAll(rows []*spanner.Row, dest interface{}) error {
 			direct.Set(reflect.Append(direct, reflect.Indirect(vp)))
 		}
 	}
-
-	return nil
 }
 
 // ScanAny a single Row into the dest map[string]interface{} or struct.
-func ScanAny(row *spanner.Row, dest interface{}) error {
+func ScanAny(m *reflectx.Mapper, row *spanner.Row, dest interface{}) error {
 	value := reflect.ValueOf(dest)
 	if value.Kind() != reflect.Ptr {
-		return errors.New("scansqlx: must pass a pointer, not a value, to Struct destination")
+		return errors.New("spansqlx: must pass a pointer, not a value, to Struct destination")
 	}
 	if value.IsNil() {
-		return errors.New("scansqlx: nil pointer passed to Struct destination")
+		return errors.New("spansqlx: nil pointer passed to Struct destination")
 	}
 
 	base := reflectx.Deref(value.Type())
 
 	var err error
 
-	switch base.Kind() {
-	case reflect.Struct:
-		err = row.ToStruct(dest)
+	switch {
+	case !reflectx.IsScannable(base):
+		err = scanStruct(m, row, value)
 	default:
 		err = row.Columns(dest)
 	}
@@ -87,3 +124,26 @@ func ScanAny(row *spanner.Row, dest interface{}) error {
 
 	return nil
 }
+
+// scanStruct scans every column of row into the field of the struct pointed
+// to by v that m maps to the column name.
+func scanStruct(m *reflectx.Mapper, row *spanner.Row, v reflect.Value) error {
+	sm := m.TypeMap(v.Type())
+
+	for i, name := range row.ColumnNames() {
+		fi, err := sm.Lookup(name)
+		if err != nil {
+			return err
+		}
+		if fi == nil {
+			return fmt.Errorf("spansqlx: missing destination name %s in %s", name, v.Type())
+		}
+
+		f := reflectx.FieldByIndexes(v, fi.Index)
+		if err := row.Column(i, f.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
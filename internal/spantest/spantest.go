@@ -0,0 +1,108 @@
+// Package spantest starts in-memory spanner servers for the tests of
+// spansqlx and its sub packages.
+package spantest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/spannertest"
+	"cloud.google.com/go/spanner/spansql"
+	"google.golang.org/api/option"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	spannerpb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Database is the database path of the clients.
+const Database = "projects/p/instances/i/databases/d"
+
+// NewClient returns a client of a new spannertest server with the tables of
+// the DDL schema. The server and the client are closed with the test.
+func NewClient(t testing.TB, schema string) *spanner.Client {
+	t.Helper()
+
+	client, err := spanner.NewClient(context.Background(), Database, option.WithGRPCConn(NewConn(t, schema)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+// NewConn returns a connection to a new spannertest server with the tables
+// of the DDL schema, for the clients of the spanner and database admin
+// services. The server is closed with the test.
+func NewConn(t testing.TB, schema string) *grpc.ClientConn {
+	t.Helper()
+
+	srv, err := spannertest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetLogger(t.Logf)
+	t.Cleanup(srv.Close)
+
+	ddl, err := spansql.ParseDDL("schema", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.UpdateDDL(ddl); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, srv.Addr, grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(fakeInterceptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// fakeInterceptor fakes the methods which spannertest does not implement.
+// ExecuteBatchDml is run as one ExecuteSql per statement, and PartitionQuery
+// returns a single partition, which spannertest reads as the whole query.
+func fakeInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	switch method {
+	case "/google.spanner.v1.Spanner/ExecuteBatchDml":
+	case "/google.spanner.v1.Spanner/PartitionQuery":
+		resp := reply.(*spannerpb.PartitionResponse)
+		resp.Partitions = []*spannerpb.Partition{{PartitionToken: []byte("all")}}
+		return nil
+	default:
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	batch := req.(*spannerpb.ExecuteBatchDmlRequest)
+	resp := reply.(*spannerpb.ExecuteBatchDmlResponse)
+	for i, s := range batch.Statements {
+		rs := new(spannerpb.ResultSet)
+		err := invoker(ctx, "/google.spanner.v1.Spanner/ExecuteSql", &spannerpb.ExecuteSqlRequest{
+			Session:     batch.Session,
+			Transaction: batch.Transaction,
+			Sql:         s.Sql,
+			Params:      s.Params,
+			ParamTypes:  s.ParamTypes,
+			Seqno:       batch.Seqno*1000 + int64(i),
+		}, rs, cc, opts...)
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			resp.Status = status.Convert(err).Proto()
+			return nil
+		}
+		resp.ResultSets = append(resp.ResultSets, rs)
+	}
+	resp.Status = &rpcstatus.Status{}
+	return nil
+}
@@ -0,0 +1,180 @@
+package internal
+
+import "strings"
+
+// tokenKind is the kind of a token of a GoogleSQL statement.
+type tokenKind int
+
+const (
+	// tokenOther is any other single character, e.g. a space or ?.
+	tokenOther tokenKind = iota
+	tokenComment
+	// tokenString is a string or bytes literal, prefix included.
+	tokenString
+	tokenQuotedIdent
+	// tokenIdent is an identifier or a keyword.
+	tokenIdent
+	tokenNumber
+	// tokenParam is a query parameter such as @id.
+	tokenParam
+	// tokenSysVar is a system variable such as @@optimizer_version.
+	tokenSysVar
+	// tokenHint is a statement hint such as @{FORCE_INDEX=Idx}.
+	tokenHint
+)
+
+// token is the token of kind at sql[start:end].
+type token struct {
+	kind       tokenKind
+	start, end int
+}
+
+// scanTokens calls fn with the tokens of the GoogleSQL statement sql in
+// order, until fn returns false. Statements which cannot be tokenized fail
+// with a *SyntaxError, once fn was called with the tokens before the error.
+func scanTokens(sql string, fn func(t token) bool) error {
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		t := token{kind: tokenOther, start: i, end: i + 1}
+
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#':
+			t.kind, t.end = tokenComment, skipLine(sql, i)
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return syntaxError(sql, i, "unterminated comment")
+			}
+			t.kind, t.end = tokenComment, i+2+end+2
+		case c == '\'' || c == '"':
+			end, err := skipString(sql, i)
+			if err != nil {
+				return err
+			}
+			t.kind, t.end = tokenString, end
+		case c == '`':
+			end, err := skipQuoted(sql, i, "`", "unterminated quoted identifier")
+			if err != nil {
+				return err
+			}
+			t.kind, t.end = tokenQuotedIdent, end
+		case c == '@':
+			switch {
+			case strings.HasPrefix(sql[i:], "@{"):
+				end, err := skipHint(sql, i)
+				if err != nil {
+					return err
+				}
+				t.kind, t.end = tokenHint, end
+			case strings.HasPrefix(sql[i:], "@@"):
+				t.kind, t.end = tokenSysVar, skipIdent(sql, i+2)
+			case i+1 < len(sql) && isIdentStart(sql[i+1]):
+				t.kind, t.end = tokenParam, skipIdent(sql, i+1)
+			default:
+				return syntaxError(sql, i, "invalid query parameter")
+			}
+		case isIdentStart(c):
+			t.kind, t.end = tokenIdent, skipIdent(sql, i)
+			// string prefixes such as r, b or rb
+			if end := t.end; end < len(sql) && (sql[end] == '\'' || sql[end] == '"') && isStringPrefix(sql[i:end]) {
+				end, err := skipString(sql, end)
+				if err != nil {
+					return err
+				}
+				t.kind, t.end = tokenString, end
+			}
+		case '0' <= c && c <= '9' || c == '.' && i+1 < len(sql) && '0' <= sql[i+1] && sql[i+1] <= '9':
+			end := i + 1
+			for end < len(sql) && (isIdentPart(sql[end]) || sql[end] == '.' ||
+				(sql[end] == '+' || sql[end] == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E')) {
+				end++
+			}
+			t.kind, t.end = tokenNumber, end
+		}
+
+		if !fn(t) {
+			return nil
+		}
+		i = t.end
+	}
+	return nil
+}
+
+func isStringPrefix(s string) bool {
+	switch strings.ToLower(s) {
+	case "r", "b", "rb", "br":
+		return true
+	}
+	return false
+}
+
+// skipLine returns the offset of the line following offset i.
+func skipLine(sql string, i int) int {
+	if end := strings.IndexByte(sql[i:], '\n'); end != -1 {
+		return i + end + 1
+	}
+	return len(sql)
+}
+
+// skipString returns the offset following the string literal starting at i.
+func skipString(sql string, i int) (int, error) {
+	q := sql[i : i+1]
+	if triple := strings.Repeat(q, 3); strings.HasPrefix(sql[i:], triple) {
+		return skipQuoted(sql, i, triple, "unterminated triple quoted string")
+	}
+	return skipQuoted(sql, i, q, "unterminated string")
+}
+
+// skipQuoted returns the offset following the closing quote of the token
+// opened by quote at i. Backslash escapes the next character, which is also
+// true of raw strings as far as the closing quote is concerned.
+func skipQuoted(sql string, i int, quote, msg string) (int, error) {
+	for j := i + len(quote); j < len(sql); j++ {
+		switch {
+		case sql[j] == '\\':
+			j++
+		case strings.HasPrefix(sql[j:], quote):
+			return j + len(quote), nil
+		}
+	}
+	return 0, syntaxError(sql, i, msg)
+}
+
+// skipHint returns the offset following the statement hint starting at i.
+func skipHint(sql string, i int) (int, error) {
+	depth := 0
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j + 1, nil
+			}
+		case '\'', '"', '`':
+			end, err := skipString(sql, j)
+			if err != nil {
+				return 0, err
+			}
+			j = end - 1
+		}
+	}
+	return 0, syntaxError(sql, i, "unterminated statement hint")
+}
+
+// skipIdent returns the offset following the identifier starting at i.
+func skipIdent(sql string, i int) int {
+	for i < len(sql) && isIdentPart(sql[i]) {
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9'
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanTokens(t *testing.T) {
+	sql := "@{FORCE_INDEX=Idx} SELECT `a b`, r'x', 1.5e-3, @@v FROM t -- c\nWHERE x=@x AND y=?"
+
+	var got []string
+	err := scanTokens(sql, func(tok token) bool {
+		if tok.kind != tokenOther {
+			got = append(got, sql[tok.start:tok.end])
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"@{FORCE_INDEX=Idx}", "SELECT", "`a b`", "r'x'", "1.5e-3", "@@v", "FROM", "t", "-- c\n",
+		"WHERE", "x", "@x", "AND", "y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got tokens %q, want %q", got, want)
+	}
+
+	// tokens before the stop are scanned only.
+	var n int
+	if err := scanTokens(`SELECT 'unterminated`, func(tok token) bool {
+		n++
+		return false
+	}); err != nil || n != 1 {
+		t.Fatalf("got %d tokens, error %v", n, err)
+	}
+	if err := scanTokens(`SELECT @1`, func(token) bool { return true }); err == nil {
+		t.Fatal("expected syntax error")
+	}
+}
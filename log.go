@@ -0,0 +1,190 @@
+package spansqlx
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// LogLevel is the severity of a log entry. The levels have the values of
+// the log/slog levels.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = -4
+	LogInfo  LogLevel = 0
+	LogWarn  LogLevel = 4
+	LogError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Logger receives an entry per operation run by a DB. keyvals are
+// alternating keys and values, e.g. "sql", "SELECT 1", "duration", d.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc is a Logger function.
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+// Log calls f.
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+// SugaredLogger is the part of *zap.SugaredLogger used by ZapLogger.
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// ZapLogger adapts a zap logger, e.g. ZapLogger(logger.Sugar()).
+func ZapLogger(l SugaredLogger) Logger {
+	return LoggerFunc(func(_ context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		switch {
+		case level >= LogError:
+			l.Errorw(msg, keyvals...)
+		case level >= LogWarn:
+			l.Warnw(msg, keyvals...)
+		case level >= LogInfo:
+			l.Infow(msg, keyvals...)
+		default:
+			l.Debugw(msg, keyvals...)
+		}
+	})
+}
+
+// Redactor returns the value of the parameter name as it is logged.
+type Redactor func(name string, value interface{}) interface{}
+
+// Redacted replaces the values of redacted parameters in logs.
+const Redacted = "[REDACTED]"
+
+// RedactAll logs no parameter value. It is the default.
+func RedactAll(name string, value interface{}) interface{} {
+	return Redacted
+}
+
+// RedactNone logs every parameter value.
+func RedactNone(name string, value interface{}) interface{} {
+	return value
+}
+
+// RedactParams only redacts the values of the given parameters, matched
+// case-insensitively.
+func RedactParams(names ...string) Redactor {
+	return func(name string, value interface{}) interface{} {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return Redacted
+			}
+		}
+		return value
+	}
+}
+
+// WithLogger logs every statement to l. Logging is disabled by default.
+// Statements are logged at LogDebug, or at the level set by WithLogLevel,
+// and failed statements at LogError.
+func WithLogger(l Logger) Option {
+	return func(o *Options) error {
+		o.logger = l
+		return nil
+	}
+}
+
+// WithLogLevel sets the level of the log entries of successful statements,
+// LogDebug by default.
+func WithLogLevel(level LogLevel) Option {
+	return func(o *Options) error {
+		o.logLevel = level
+		return nil
+	}
+}
+
+// WithLogRedactor sets how parameter values are logged, RedactAll by
+// default.
+func WithLogRedactor(r Redactor) Option {
+	return func(o *Options) error {
+		if r != nil {
+			o.redactor = r
+		}
+		return nil
+	}
+}
+
+// logOperation logs the operation op once done.
+func (d *DB) logOperation(ctx context.Context, op *Operation) {
+	l := d.opts.logger
+	if l == nil {
+		return
+	}
+
+	keyvals := []interface{}{"duration", op.Duration}
+	switch len(op.Statements) {
+	case 0:
+	case 1:
+		keyvals = append(keyvals, "sql", op.Statements[0].SQL, "params", d.redact(op.Statements[0].Params))
+	default:
+		sql := make([]string, len(op.Statements))
+		params := make([]map[string]interface{}, len(op.Statements))
+		for i := range op.Statements {
+			sql[i] = op.Statements[i].SQL
+			params[i] = d.redact(op.Statements[i].Params)
+		}
+		keyvals = append(keyvals, "sql", sql, "params", params)
+	}
+	if len(op.Mutations) > 0 {
+		keyvals = append(keyvals, "mutations", len(op.Mutations))
+	}
+	if op.Kind.Read() {
+		keyvals = append(keyvals, "rows", op.Rows)
+	} else if len(op.Statements) > 0 {
+		keyvals = append(keyvals, "rows_affected", op.Rows)
+	}
+	keyvals = append(keyvals, "tx", string(op.TxMode))
+	if op.TxID != "" {
+		keyvals = append(keyvals, "tx_id", op.TxID)
+	}
+	if op.Attempt > 0 {
+		keyvals = append(keyvals, "attempt", op.Attempt)
+	}
+	if op.RequestTag != "" {
+		keyvals = append(keyvals, "request_tag", op.RequestTag)
+	}
+
+	level := d.opts.logLevel
+	if op.Err != nil {
+		level = LogError
+		keyvals = append(keyvals, "error", op.Err)
+	}
+
+	l.Log(ctx, level, "spansqlx: "+string(op.Kind), keyvals...)
+}
+
+// redact returns the logged values of params.
+func (d *DB) redact(params map[string]interface{}) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(params))
+	for name, v := range params {
+		out[name] = d.opts.redactor(name, v)
+	}
+	return out
+}
@@ -0,0 +1,15 @@
+//go:build go1.21
+
+package spansqlx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger adapts a log/slog logger.
+func SlogLogger(l *slog.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		l.Log(ctx, slog.Level(level), msg, keyvals...)
+	})
+}
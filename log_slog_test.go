@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package spansqlx_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := spansqlx.SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	_, client := newTestDB(t)
+	ctx := context.Background()
+
+	db := spansqlx.NewDb(ctx, client, spansqlx.WithLogger(logger))
+	var n int64
+	if err := db.Get(ctx, &n, `SELECT COUNT(*) FROM Singers`); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, `msg="spansqlx: query"`) ||
+		!strings.Contains(out, "rows=1") {
+		t.Fatalf("got log %q", out)
+	}
+}
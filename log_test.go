@@ -0,0 +1,72 @@
+package spansqlx_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+func TestLogger(t *testing.T) {
+	type entry struct {
+		level   spansqlx.LogLevel
+		msg     string
+		keyvals map[string]interface{}
+	}
+	var (
+		mu      sync.Mutex
+		entries []entry
+	)
+	logger := spansqlx.LoggerFunc(func(ctx context.Context, level spansqlx.LogLevel, msg string, keyvals ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		e := entry{level: level, msg: msg, keyvals: make(map[string]interface{})}
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			e.keyvals[keyvals[i].(string)] = keyvals[i+1]
+		}
+		entries = append(entries, e)
+	})
+
+	_, client := newTestDB(t)
+	ctx := context.Background()
+
+	db := spansqlx.NewDb(ctx, client, spansqlx.WithLogger(logger))
+	var albums []Album
+	if err := db.Select(ctx, &albums, `SELECT * FROM Albums WHERE SingerID = @id`, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE Missing SET Name = 'x' WHERE true`); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("got %d log entries, want 3", len(entries))
+	}
+	e := entries[0]
+	if e.level != spansqlx.LogDebug || e.msg != "spansqlx: query" || e.keyvals["rows"] != int64(3) || e.keyvals["tx"] != "single" {
+		t.Fatalf("got entry %+v", e)
+	}
+	if params := e.keyvals["params"].(map[string]interface{}); params["id"] != spansqlx.Redacted {
+		t.Fatalf("got params %v, want redacted", params)
+	}
+	if e := entries[1]; e.level != spansqlx.LogError || e.keyvals["error"] == nil || e.keyvals["tx_id"] == nil {
+		t.Fatalf("got entry %+v", e)
+	}
+	// followed by its transaction of its own.
+	if e := entries[2]; e.msg != "spansqlx: transaction" || e.level != spansqlx.LogError || e.keyvals["tx_id"] != entries[1].keyvals["tx_id"] {
+		t.Fatalf("got entry %+v", e)
+	}
+
+	entries = nil
+	db = spansqlx.NewDb(ctx, client, spansqlx.WithLogger(logger),
+		spansqlx.WithLogLevel(spansqlx.LogInfo), spansqlx.WithLogRedactor(spansqlx.RedactParams("last")))
+	if _, err := db.Exec(ctx, `UPDATE Singers SET LastName = @last WHERE SingerID = @id`, "Secret", 1); err != nil {
+		t.Fatal(err)
+	}
+	e = entries[0]
+	params := e.keyvals["params"].(map[string]interface{})
+	if e.level != spansqlx.LogInfo || e.keyvals["rows_affected"] != int64(1) || params["last"] != spansqlx.Redacted || params["id"] != 1 {
+		t.Fatalf("got entry %+v", e)
+	}
+}
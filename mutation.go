@@ -0,0 +1,108 @@
+package spansqlx
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+)
+
+// MutationOption selects the columns written by struct mutations.
+type MutationOption func(*internal.ColumnFilter)
+
+// WithColumns only writes the given columns, e.g. the key and the changed
+// columns of an Update.
+func WithColumns(columns ...string) MutationOption {
+	return func(f *internal.ColumnFilter) {
+		f.Include = append(f.Include, columns...)
+	}
+}
+
+// WithoutColumns never writes the given columns.
+func WithoutColumns(columns ...string) MutationOption {
+	return func(f *internal.ColumnFilter) {
+		f.Exclude = append(f.Exclude, columns...)
+	}
+}
+
+// Insert rows into table with mutations.
+// arg is a struct, a map with string keys, or a slice of either. Columns are
+// named by the struct fields as with NamedExec, fields of embedded structs
+// are promoted but nested structs must be excluded WithoutColumns.
+// Inside a TxPipeline the mutations are buffered in the transaction,
+// otherwise they are applied at once.
+func (d *DB) Insert(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return d.mutate(ctx, spanner.Insert, table, arg, opts)
+}
+
+// Update rows of table with mutations, as Insert does.
+// Every column of arg is written. WithColumns limits the update to the given
+// columns, which must include the key columns of table.
+func (d *DB) Update(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return d.mutate(ctx, spanner.Update, table, arg, opts)
+}
+
+// InsertOrUpdate rows of table with mutations, as Insert does.
+func (d *DB) InsertOrUpdate(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return d.mutate(ctx, spanner.InsertOrUpdate, table, arg, opts)
+}
+
+// Replace rows of table with mutations, as Insert does. Columns which are
+// not written are reset to NULL.
+func (d *DB) Replace(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return d.mutate(ctx, spanner.Replace, table, arg, opts)
+}
+
+// Delete the rows of table at keys with a mutation, e.g. spanner.Key{1} or
+// spanner.AllKeys().
+func (d *DB) Delete(ctx context.Context, table string, keys spanner.KeySet) error {
+	return withTable(d.Apply(ctx, spanner.Delete(table, keys)), table)
+}
+
+// Apply the mutations ms.
+// Inside a TxPipeline the mutations are buffered in the transaction,
+// otherwise they are applied at once.
+func (d *DB) Apply(ctx context.Context, ms ...*spanner.Mutation) error {
+	// checks tx in context.
+	if tx, ok := hasReadWriteTxContext(ctx); ok {
+		ctx, op, err := d.startOperation(ctx, OpApply, tx, nil, ms)
+		if err == nil {
+			err = tx.BufferWrite(op.Mutations)
+		}
+		err = d.endOperation(ctx, op, 0, err)
+		return err
+	}
+
+	_, err := d.apply(ctx, ms)
+	return err
+}
+
+// apply commits the mutations ms in a transaction of their own.
+func (d *DB) apply(ctx context.Context, ms []*spanner.Mutation) (time.Time, error) {
+	ctx, op, err := d.startOperation(ctx, OpApply, nil, nil, ms)
+	var ts time.Time
+	if err == nil {
+		ts, err = d.db.Apply(ctx, op.Mutations)
+	}
+	err = d.endOperation(ctx, op, 0, err)
+	return ts, err
+}
+
+// mutate builds the mutations of op from arg and applies them.
+func (d *DB) mutate(ctx context.Context, op internal.MutationOp, table string, arg interface{}, opts []MutationOption) error {
+	ms, err := d.prepareMutations(op, table, arg, opts)
+	if err != nil || len(ms) == 0 {
+		return err
+	}
+	return withTable(d.Apply(ctx, internal.SpannerMutations(ms)...), table)
+}
+
+// prepareMutations builds the mutations of op from arg.
+func (d *DB) prepareMutations(op internal.MutationOp, table string, arg interface{}, opts []MutationOption) ([]internal.Mutation, error) {
+	var filter internal.ColumnFilter
+	for i := range opts {
+		opts[i](&filter)
+	}
+	return internal.PrepareMutations(d.opts.mapper, op, table, arg, filter)
+}
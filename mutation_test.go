@@ -0,0 +1,76 @@
+package spansqlx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestMutations(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	count := func() (n int64) {
+		t.Helper()
+		if err := db.Get(ctx, &n, `SELECT COUNT(*) FROM Albums`); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	newAlbums := []*Album{
+		{SingerID: 3, AlbumID: 1, AlbumTitle: "Nothing"},
+		{SingerID: 3, AlbumID: 2, AlbumTitle: "Something"},
+	}
+	if err := db.Insert(ctx, "Albums", newAlbums); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != int64(len(allAlbums)+2) {
+		t.Fatalf("got %d albums after insert", n)
+	}
+
+	// buffered in the transaction of the pipeline
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		if err := db.Update(ctx, "Albums", Album{SingerID: 3, AlbumID: 1, AlbumTitle: "Everything"},
+			spansqlx.WithColumns("SingerID", "AlbumID", "AlbumTitle")); err != nil {
+			return err
+		}
+		if err := db.InsertOrUpdate(ctx, "Albums", map[string]interface{}{
+			"SingerID": int64(4), "AlbumID": int64(1), "AlbumTitle": "Lea", "Ignored": true,
+		}, spansqlx.WithoutColumns("Ignored")); err != nil {
+			return err
+		}
+		return db.Delete(ctx, "Albums", spanner.Key{3, 2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	if err := db.Select(ctx, &titles, `SELECT AlbumTitle FROM Albums WHERE SingerID > 2 ORDER BY SingerID, AlbumID`); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(titles, ",") != "Everything,Lea" {
+		t.Fatalf("got titles %v", titles)
+	}
+
+	if err := db.Update(ctx, "Albums", Album{}, spansqlx.WithColumns("Missing")); err == nil {
+		t.Fatal("expected unknown column error")
+	}
+
+	// the fields of nested structs are not columns.
+	type ownedAlbum struct {
+		Album
+		Owner struct{ Name string }
+	}
+	owned := ownedAlbum{Album: Album{SingerID: 5, AlbumID: 1, AlbumTitle: "Owned"}}
+	if err := db.Insert(ctx, "Albums", owned); err == nil || !strings.Contains(err.Error(), "Owner.Name") {
+		t.Fatalf("got error %v, want nested field error", err)
+	}
+	if err := db.Insert(ctx, "Albums", owned, spansqlx.WithoutColumns("Owner.Name")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+package spansqlx
+
+import (
+	"context"
+
+	"github.com/reiot101/spansqlx/internal"
+)
+
+// NamedGet within a transaction.
+// Named parameters are bound from the fields of a struct or the keys of a
+// map, as NamedExec does.
+// An error is returned if the result set is empty.
+func (d *DB) NamedGet(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	stmt, err := internal.PrepareStmtAny(d.opts.mapper, sql, arg)
+	if err != nil {
+		return err
+	}
+	return d.GetX(ctx, dest, stmt, opts...)
+}
+
+// NamedSelect within a transaction.
+// Named parameters are bound from the fields of a struct or the keys of a
+// map, as NamedExec does.
+func (d *DB) NamedSelect(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	stmt, err := internal.PrepareStmtAny(d.opts.mapper, sql, arg)
+	if err != nil {
+		return err
+	}
+	return d.SelectX(ctx, dest, stmt, opts...)
+}
+
+// NamedQuery queries the database and returns a *Rows cursor.
+// Named parameters are bound from the fields of a struct or the keys of a
+// map, as NamedExec does.
+// The caller must Close the returned Rows.
+func (d *DB) NamedQuery(ctx context.Context, sql string, arg interface{}, opts ...ReadOption) (*Rows, error) {
+	stmt, err := internal.PrepareStmtAny(d.opts.mapper, sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	return d.QueryRowsX(ctx, stmt, opts...)
+}
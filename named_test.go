@@ -0,0 +1,90 @@
+package spansqlx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestNamedSelect(t *testing.T) {
+	db, client := newTestDB(t)
+
+	var albums []Album
+	if err := db.NamedSelect(context.Background(), &albums,
+		`SELECT SingerID, AlbumID, AlbumTitle FROM Albums WHERE SingerID = @SingerID AND AlbumID > @AlbumID ORDER BY AlbumID`,
+		Album{SingerID: 2, AlbumID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != 2 || albums[0] != allAlbums[3] || albums[1] != allAlbums[4] {
+		t.Fatalf("unexpected albums %+v", albums)
+	}
+
+	// within a transaction found in the context
+	_, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		var singer Singer
+		return db.NamedGet(spansqlx.SetTxContext(ctx, tx), &singer,
+			`SELECT SingerID, FirstName, LastName FROM Singers WHERE LastName = @last`,
+			map[string]interface{}{"last": "Martin"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.NamedQuery(context.Background(),
+		`SELECT AlbumTitle FROM Albums WHERE SingerID = @id OR AlbumID = @id`, map[string]interface{}{"id": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, title)
+	}
+	if err := rows.Err(); err != nil || len(titles) != 1 || titles[0] != "Terrified" {
+		t.Fatalf("got (%v, %v), want [Terrified]", titles, err)
+	}
+}
+
+func TestNamedSelectValidation(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	var albums []Album
+	err := db.NamedSelect(context.Background(), &albums,
+		`SELECT * FROM Albums WHERE SingerID = @SingerID AND AlbumTitle = @Title`, Album{})
+	if err == nil || !strings.Contains(err.Error(), "missing value for parameter @Title") {
+		t.Fatalf("expected missing value error, got %v", err)
+	}
+
+	err = db.NamedSelect(context.Background(), &albums,
+		`SELECT * FROM Albums WHERE SingerID = @id`, map[string]interface{}{"id": 1, "typo": 2})
+	if err == nil || !strings.Contains(err.Error(), "unused values for typo") {
+		t.Fatalf("expected unused value error, got %v", err)
+	}
+
+	// maps and structs match parameters regardless of case.
+	if err := db.NamedSelect(context.Background(), &albums,
+		`SELECT * FROM Albums WHERE SingerID = @singerid`, map[string]interface{}{"SingerID": 2}); err != nil || len(albums) != 3 {
+		t.Fatalf("got %d albums, error %v", len(albums), err)
+	}
+	err = db.NamedSelect(context.Background(), &albums,
+		`SELECT * FROM Albums WHERE SingerID = @id`, map[string]interface{}{"id": 1, "ID": 2})
+	if err == nil || !strings.Contains(err.Error(), "matches ID, id, which only differ by case") {
+		t.Fatalf("expected case collision error, got %v", err)
+	}
+	type caseCollision struct {
+		ID int64 `db:"ID"`
+		Id int64 `db:"Id"`
+	}
+	err = db.NamedSelect(context.Background(), &albums,
+		`SELECT * FROM Albums WHERE SingerID = @id`, caseCollision{})
+	if err == nil || !strings.Contains(err.Error(), "matches ID, Id, which only differ by case") {
+		t.Fatalf("expected case collision error, got %v", err)
+	}
+}
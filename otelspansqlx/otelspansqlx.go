@@ -0,0 +1,147 @@
+// Package otelspansqlx traces the operations of a spansqlx.DB with
+// OpenTelemetry.
+//
+//	db, err := spansqlx.Open(ctx, spansqlx.WithDatabase(database),
+//		spansqlx.WithInterceptors(otelspansqlx.NewInterceptor()))
+//
+// Each query, statement, batch and mutation is a client span, nested under
+// the span of the TxPipeline or ReadOnlyPipeline it runs in.
+package otelspansqlx
+
+import (
+	"context"
+	"strings"
+
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/reiot101/spansqlx/otelspansqlx"
+
+// Attributes of the spans, besides the db.* semantic conventions.
+const (
+	ParamsKey    = attribute.Key("spansqlx.params")
+	MutationsKey = attribute.Key("spansqlx.mutations")
+	RowsKey      = attribute.Key("spansqlx.rows")
+	TxModeKey    = attribute.Key("spansqlx.tx_mode")
+	TxIDKey      = attribute.Key("spansqlx.tx_id")
+	AttemptKey   = attribute.Key("spansqlx.attempt")
+)
+
+// Option configures the interceptor.
+type Option func(*config)
+
+type config struct {
+	provider trace.TracerProvider
+	sanitize func(sql string) string
+	attrs    []attribute.KeyValue
+}
+
+// WithTracerProvider sets the provider of the tracer, the global provider by
+// default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = tp
+	}
+}
+
+// WithSanitizer records the statements as returned by f, e.g. SanitizeSQL.
+// Statements are recorded as is by default, parameter values never are.
+func WithSanitizer(f func(sql string) string) Option {
+	return func(c *config) {
+		c.sanitize = f
+	}
+}
+
+// WithAttributes adds attrs to every span, e.g. semconv.DBNameKey.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// SanitizeSQL replaces the string, bytes and number literals of sql with ?.
+func SanitizeSQL(sql string) string {
+	return internal.SanitizeLiterals(sql)
+}
+
+type interceptor struct {
+	tracer   trace.Tracer
+	sanitize func(sql string) string
+	attrs    []attribute.KeyValue
+}
+
+// NewInterceptor returns a spansqlx.Interceptor which records a span per
+// operation.
+func NewInterceptor(opts ...Option) spansqlx.Interceptor {
+	c := config{provider: otel.GetTracerProvider()}
+	for i := range opts {
+		opts[i](&c)
+	}
+
+	return &interceptor{
+		tracer:   c.provider.Tracer(instrumentationName),
+		sanitize: c.sanitize,
+		attrs:    c.attrs,
+	}
+}
+
+// Before starts the span of op.
+func (i *interceptor) Before(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+	attrs := append([]attribute.KeyValue{
+		semconv.DBSystemKey.String("spanner"),
+		semconv.DBOperationKey.String(string(op.Kind)),
+		TxModeKey.String(string(op.TxMode)),
+	}, i.attrs...)
+
+	if len(op.Statements) > 0 {
+		sql := make([]string, len(op.Statements))
+		params := 0
+		for j, s := range op.Statements {
+			sql[j] = s.SQL
+			if i.sanitize != nil {
+				sql[j] = i.sanitize(s.SQL)
+			}
+			params += len(s.Params)
+		}
+		attrs = append(attrs, semconv.DBStatementKey.String(strings.Join(sql, ";\n")), ParamsKey.Int(params))
+	}
+	if len(op.Mutations) > 0 {
+		attrs = append(attrs, MutationsKey.Int(len(op.Mutations)))
+	}
+
+	kind := trace.SpanKindClient
+	if op.Kind == spansqlx.OpTransaction {
+		kind = trace.SpanKindInternal
+	}
+
+	ctx, _ = i.tracer.Start(ctx, "spansqlx."+strings.ReplaceAll(string(op.Kind), " ", "_"),
+		trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	return ctx, nil
+}
+
+// After ends the span of op.
+func (i *interceptor) After(ctx context.Context, op *spansqlx.Operation) {
+	span := trace.SpanFromContext(ctx)
+
+	if op.Kind.Read() || len(op.Statements) > 0 {
+		span.SetAttributes(RowsKey.Int64(op.Rows))
+	}
+	if op.TxID != "" {
+		span.SetAttributes(TxIDKey.String(op.TxID))
+	}
+	if op.Attempt > 0 {
+		span.SetAttributes(AttemptKey.Int(op.Attempt))
+	}
+	if op.Err != nil {
+		span.RecordError(op.Err)
+		span.SetStatus(codes.Error, op.Err.Error())
+	}
+
+	span.End()
+}
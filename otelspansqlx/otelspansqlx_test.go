@@ -0,0 +1,103 @@
+package otelspansqlx_test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal/spantest"
+	"github.com/reiot101/spansqlx/otelspansqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestDB(t *testing.T, opts ...spansqlx.Option) *spansqlx.DB {
+	t.Helper()
+
+	client := spantest.NewClient(t, `CREATE TABLE Singers (
+	SingerID INT64 NOT NULL,
+	Name STRING(1024),
+) PRIMARY KEY (SingerID)`)
+
+	if _, err := client.Apply(context.Background(), []*spanner.Mutation{
+		spanner.Insert("Singers", []string{"SingerID", "Name"}, []interface{}{1, "Marc"}),
+		spanner.Insert("Singers", []string{"SingerID", "Name"}, []interface{}{2, "Catalina"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return spansqlx.NewDb(context.Background(), client, opts...)
+}
+
+func attrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestInterceptor(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	db := newTestDB(t, spansqlx.WithInterceptors(otelspansqlx.NewInterceptor(
+		otelspansqlx.WithTracerProvider(tp),
+		otelspansqlx.WithSanitizer(otelspansqlx.SanitizeSQL),
+	)))
+	ctx := context.Background()
+
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		var names []string
+		if err := db.Select(ctx, &names, `SELECT Name FROM Singers WHERE SingerID > @id`, 0); err != nil {
+			return err
+		}
+		_, err := db.Exec(ctx, `UPDATE Singers SET Name = 'Mark' WHERE SingerID = @id`, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE Missing SET Name = 'x' WHERE true`); err == nil {
+		t.Fatal("expected error")
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 5 {
+		t.Fatalf("got %d spans, want 5", len(spans))
+	}
+	query, exec, tx, failed, implicit := spans[0], spans[1], spans[2], spans[3], spans[4]
+
+	if tx.Name() != "spansqlx.transaction" || attrs(tx)[otelspansqlx.AttemptKey].AsInt64() != 1 {
+		t.Fatalf("got transaction span %s %v", tx.Name(), attrs(tx))
+	}
+	for _, s := range []sdktrace.ReadOnlySpan{query, exec} {
+		if s.Parent().SpanID() != tx.SpanContext().SpanID() {
+			t.Fatalf("span %s is not nested in the transaction", s.Name())
+		}
+		if attrs(s)[otelspansqlx.TxModeKey].AsString() != "read-write" {
+			t.Fatalf("got span %s %v", s.Name(), attrs(s))
+		}
+	}
+
+	a := attrs(query)
+	if query.Name() != "spansqlx.query" || a["db.statement"].AsString() != `SELECT Name FROM Singers WHERE SingerID > @id` ||
+		a[otelspansqlx.ParamsKey].AsInt64() != 1 || a[otelspansqlx.RowsKey].AsInt64() != 2 {
+		t.Fatalf("got query span %s %v", query.Name(), a)
+	}
+	a = attrs(exec)
+	if a["db.statement"].AsString() != `UPDATE Singers SET Name = ? WHERE SingerID = @id` || a[otelspansqlx.RowsKey].AsInt64() != 1 {
+		t.Fatalf("got exec span %s %v", exec.Name(), a)
+	}
+
+	// a statement outside of a pipeline runs in a transaction of its own.
+	if failed.Status().Code != codes.Error || failed.Parent().SpanID() != implicit.SpanContext().SpanID() {
+		t.Fatalf("got failed span %s %v", failed.Name(), failed.Status())
+	}
+	if implicit.Name() != "spansqlx.transaction" || implicit.Status().Code != codes.Error || implicit.Parent().IsValid() {
+		t.Fatalf("got transaction span %s %v", implicit.Name(), implicit.Status())
+	}
+}
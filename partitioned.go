@@ -0,0 +1,230 @@
+package spansqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+)
+
+// ErrPartitionedInTx is returned when a partitioned DML statement or query is
+// run with a transaction in the context, which it cannot be part of.
+var ErrPartitionedInTx = errors.New("spansqlx: partitioned operations cannot run within a transaction")
+
+const (
+	// DefaultPartitionWorkers is the number of partitions read at once.
+	DefaultPartitionWorkers = 4
+)
+
+// PartitionOption configures a partitioned query.
+type PartitionOption func(*partitionOptions)
+
+type partitionOptions struct {
+	workers       int
+	maxPartitions int64
+}
+
+// WithPartitionWorkers sets the number of partitions read at once,
+// DefaultPartitionWorkers by default.
+func WithPartitionWorkers(n int) PartitionOption {
+	return func(o *partitionOptions) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// WithMaxPartitions hints spanner at the desired number of partitions, which
+// it chooses by default.
+func WithMaxPartitions(n int64) PartitionOption {
+	return func(o *partitionOptions) {
+		o.maxPartitions = n
+	}
+}
+
+// PartitionFunc reads the rows of a partition of a partitioned query. It is
+// called by several workers at once.
+type PartitionFunc func(ctx context.Context, partition int, rows *Rows) error
+
+// PartitionedExec executes a partitioned DML statement.
+// Any placeholder parameters are replaced with supplied args, as Exec does.
+// The statement is run on each partition of the table in transactions of
+// its own, so it must be an UPDATE or DELETE which can be applied more than
+// once to the same row, e.g. a backfill of a new column:
+//
+//	n, err := db.PartitionedExec(ctx, `UPDATE Singers SET Status = @status WHERE Status IS NULL`, "active")
+//
+// The number of rows affected is a lower bound.
+func (d *DB) PartitionedExec(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return d.PartitionedExecX(ctx, stmt)
+}
+
+// PartitionedExecX executes a partitioned DML statement.
+// Based spanner statement.
+func (d *DB) PartitionedExecX(ctx context.Context, stmt spanner.Statement) (int64, error) {
+	if hasTxContext(ctx) != nil {
+		return 0, ErrPartitionedInTx
+	}
+
+	keyword, err := internal.StatementKeyword(stmt.SQL)
+	if err != nil {
+		return 0, err
+	}
+	if keyword != "UPDATE" && keyword != "DELETE" {
+		return 0, fmt.Errorf("spansqlx: partitioned DML must be an UPDATE or DELETE statement, not %q", keyword)
+	}
+
+	ctx, op, err := d.startOperation(ctx, OpPartitionedExec, nil, []spanner.Statement{stmt}, nil)
+	var n int64
+	if err == nil {
+		n, err = d.db.PartitionedUpdateWithOptions(ctx, op.Statements[0], queryOptions(ctx))
+	}
+	err = d.endOperation(ctx, op, n, err)
+	return n, err
+}
+
+// PartitionedSelect runs a partitioned query, reading its partitions in
+// parallel from the same snapshot.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the snapshot, bounded
+// staleness fails with ErrBoundedStaleness. The query must be
+// root-partitionable, such as a scan of a table with filters.
+// fn is called once per partition, the first error cancels the reads of the
+// other partitions and is returned.
+func (d *DB) PartitionedSelect(ctx context.Context, sql string, args []interface{}, fn PartitionFunc, opts ...PartitionOption) error {
+	args, readOpts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return err
+	}
+	return d.PartitionedSelectX(ctx, stmt, fn, readOpts, opts...)
+}
+
+// PartitionedSelectX is PartitionedSelect based spanner statement.
+func (d *DB) PartitionedSelectX(ctx context.Context, stmt spanner.Statement, fn PartitionFunc, readOpts []ReadOption, opts ...PartitionOption) error {
+	if hasTxContext(ctx) != nil {
+		return ErrPartitionedInTx
+	}
+
+	o := partitionOptions{workers: DefaultPartitionWorkers}
+	for i := range opts {
+		opts[i](&o)
+	}
+
+	tb, err := newReadOptions(append(readOptionsContext(ctx), readOpts...)...).multiUseBound()
+	if err != nil {
+		return err
+	}
+
+	// the whole query is a single operation, so that its statement is
+	// rewritten before it is partitioned.
+	ctx, op, err := d.startOperation(ctx, OpPartitionedQuery, TxBatchReadOnly, []spanner.Statement{stmt}, nil)
+	if err == nil {
+		err = d.executePartitions(ctx, tb, op, fn, o)
+	}
+	err = d.endOperation(ctx, op, op.Rows, err)
+	return err
+}
+
+// executePartitions reads the partitions of the statement of op from a
+// snapshot of tb with the workers of o, and counts their rows in op.Rows.
+func (d *DB) executePartitions(ctx context.Context, tb spanner.TimestampBound, op *operation, fn PartitionFunc, o partitionOptions) error {
+	tx, err := d.db.BatchReadOnlyTransaction(ctx, tb)
+	if err != nil {
+		return err
+	}
+	defer tx.Cleanup(ctx)
+	defer tx.Close()
+
+	partitions, err := tx.PartitionQueryWithOptions(ctx, op.Statements[0], spanner.PartitionOptions{MaxPartitions: o.maxPartitions}, queryOptions(ctx))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		next  = make(chan int)
+	)
+	for w := 0; w < o.workers && w < len(partitions); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				iter := &rowIter{iter: tx.Execute(ctx, partitions[i]), op: op.Operation, stopped: func(rows int64, _ error) {
+					atomic.AddInt64(&op.Rows, rows)
+				}}
+				rows := &Rows{mapper: d.opts.mapper, iter: iter}
+				err := fn(ctx, i, rows)
+				rows.Close()
+				if err == nil {
+					err = rows.Err()
+				}
+				if err != nil {
+					once.Do(func() {
+						first = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := range partitions {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// PartitionedSelectChan runs a partitioned query as PartitionedSelect does,
+// and sends each row scanned into a T to ch, in no particular order. ch is
+// not closed.
+//
+//	ch := make(chan Singer)
+//	go func() {
+//		defer close(ch)
+//		err = spansqlx.PartitionedSelectChan(ctx, db, ch, "SELECT * FROM Singers", nil)
+//	}()
+//	for s := range ch {
+//		...
+//	}
+func PartitionedSelectChan[T any](ctx context.Context, d *DB, ch chan<- T, sql string, args []interface{}, opts ...PartitionOption) error {
+	return d.PartitionedSelect(ctx, sql, args, func(ctx context.Context, _ int, rows *Rows) error {
+		for rows.Next() {
+			var v T
+			if err := rows.StructScan(&v); err != nil {
+				return err
+			}
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return rows.Err()
+	}, opts...)
+}
@@ -0,0 +1,113 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"google.golang.org/grpc/codes"
+)
+
+func TestPartitionedExec(t *testing.T) {
+	db, client := newTestDB(t)
+	ctx := context.Background()
+
+	// spannertest has no partitioned DML, only the checks are run.
+	if _, err := db.PartitionedExec(ctx, `INSERT INTO Singers (SingerID) VALUES (@id)`, 10); err == nil {
+		t.Fatal("expected INSERT to be rejected")
+	}
+
+	_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		_, err := db.PartitionedExec(spansqlx.SetTxContext(ctx, tx), `UPDATE Singers SET LastName = 'x' WHERE true`)
+		return err
+	})
+	if !errors.Is(err, spansqlx.ErrPartitionedInTx) {
+		t.Fatalf("got error %v, want ErrPartitionedInTx", err)
+	}
+}
+
+func TestPartitionedSelect(t *testing.T) {
+	db, client := newTestDB(t)
+	ctx := context.Background()
+
+	ch := make(chan Album)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		errc <- spansqlx.PartitionedSelectChan(ctx, db, ch,
+			`SELECT SingerID, AlbumID, AlbumTitle FROM Albums WHERE SingerID = @id`, []interface{}{2},
+			spansqlx.WithPartitionWorkers(2))
+	}()
+	var albums []Album
+	for a := range ch {
+		albums = append(albums, a)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != 3 {
+		t.Fatalf("got %d albums, want 3", len(albums))
+	}
+
+	errStop := errors.New("stop")
+	err := db.PartitionedSelect(ctx, `SELECT SingerID FROM Singers`, nil, func(ctx context.Context, p int, rows *spansqlx.Rows) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got error %v, want errStop", err)
+	}
+
+	// the errors of the partitions are errors of the partitioned query.
+	err = db.PartitionedSelect(ctx, `SELECT Nope FROM Missing`, nil, func(ctx context.Context, p int, rows *spansqlx.Rows) error {
+		for rows.Next() {
+		}
+		return rows.Err()
+	})
+	var e *spansqlx.Error
+	if !errors.As(err, &e) || e.Op != spansqlx.OpPartitionedQuery || e.SQL != `SELECT Nope FROM Missing` {
+		t.Fatalf("got error %#v, want an *Error of the partitioned query", err)
+	}
+
+	err = db.PartitionedSelect(ctx, `SELECT SingerID FROM Singers`, []interface{}{spansqlx.WithMaxStaleness(time.Minute)},
+		func(ctx context.Context, p int, rows *spansqlx.Rows) error { return nil })
+	if !errors.Is(err, spansqlx.ErrBoundedStaleness) {
+		t.Fatalf("got error %v, want ErrBoundedStaleness", err)
+	}
+
+	_, err = client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		return db.PartitionedSelect(spansqlx.SetTxContext(ctx, tx), `SELECT SingerID FROM Singers`, nil,
+			func(ctx context.Context, p int, rows *spansqlx.Rows) error { return nil })
+	})
+	if !errors.Is(err, spansqlx.ErrPartitionedInTx) {
+		t.Fatalf("got error %v, want ErrPartitionedInTx", err)
+	}
+}
+
+func TestPartitionedSelectTxError(t *testing.T) {
+	_, client := newTestDB(t)
+
+	var ops []spansqlx.Operation
+	db := spansqlx.NewDb(context.Background(), client, spansqlx.WithInterceptors(spansqlx.InterceptorFuncs{
+		AfterFunc: func(ctx context.Context, op *spansqlx.Operation) {
+			ops = append(ops, *op)
+		},
+	}))
+
+	// the snapshot cannot begin with a canceled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := db.PartitionedSelect(ctx, `SELECT SingerID FROM Singers`, nil, func(ctx context.Context, p int, rows *spansqlx.Rows) error {
+		t.Fatal("partition read without a snapshot")
+		return nil
+	})
+	var e *spansqlx.Error
+	if !errors.As(err, &e) || e.Op != spansqlx.OpPartitionedQuery || e.Code != codes.Canceled {
+		t.Fatalf("got error %#v, want a canceled *Error of the partitioned query", err)
+	}
+	if len(ops) != 1 || ops[0].TxMode != spansqlx.TxBatchReadOnly || ops[0].Err == nil {
+		t.Fatalf("got operations %+v", ops)
+	}
+}
@@ -0,0 +1,186 @@
+// Package promspansqlx measures the operations of a spansqlx.DB with
+// Prometheus metrics.
+//
+//	metrics := promspansqlx.NewCollector()
+//	prometheus.MustRegister(metrics)
+//	db, err := spansqlx.Open(ctx, spansqlx.WithDatabase(database), promspansqlx.WithCollector(metrics))
+//
+// Metrics are labelled by the kind of operation and by the request tag set
+// with spansqlx.SetRequestTag, if any.
+package promspansqlx
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/reiot101/spansqlx"
+)
+
+// Option configures a Collector.
+type Option func(*config)
+
+type config struct {
+	namespace string
+	buckets   []float64
+	labels    prometheus.Labels
+}
+
+// WithNamespace prefixes the metric names with namespace, e.g.
+// myapp_spansqlx_operation_duration_seconds.
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the duration histogram, in seconds,
+// prometheus.DefBuckets by default.
+func WithBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// WithConstLabels adds labels to every metric, e.g. the database name.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		c.labels = labels
+	}
+}
+
+// Collector is a prometheus.Collector of the metrics of the operations of a
+// DB, which it receives as a spansqlx.Interceptor.
+type Collector struct {
+	duration     *prometheus.HistogramVec
+	rowsScanned  *prometheus.CounterVec
+	rowsAffected *prometheus.CounterVec
+	mutations    *prometheus.CounterVec
+	transactions *prometheus.CounterVec
+	retries      *prometheus.CounterVec
+}
+
+// NewCollector returns a Collector, which must be registered to be exposed.
+func NewCollector(opts ...Option) *Collector {
+	c := config{buckets: prometheus.DefBuckets}
+	for i := range opts {
+		opts[i](&c)
+	}
+
+	return &Collector{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "operation_duration_seconds",
+			Help:        "Duration of the queries, statements, mutations and transactions.",
+			Buckets:     c.buckets,
+			ConstLabels: c.labels,
+		}, []string{"operation", "tx_mode", "tag", "status"}),
+		rowsScanned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "rows_scanned_total",
+			Help:        "Rows returned by queries.",
+			ConstLabels: c.labels,
+		}, []string{"operation", "tag"}),
+		rowsAffected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "rows_affected_total",
+			Help:        "Rows inserted, updated or deleted by DML statements.",
+			ConstLabels: c.labels,
+		}, []string{"operation", "tag"}),
+		mutations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "mutations_total",
+			Help:        "Mutations applied or buffered.",
+			ConstLabels: c.labels,
+		}, []string{"tx_mode", "tag"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "transactions_total",
+			Help:        "Read-write transactions of TxPipeline, by result: commit, abort or error.",
+			ConstLabels: c.labels,
+		}, []string{"tx_mode", "tag", "result"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "spansqlx",
+			Name:        "transaction_retries_total",
+			Help:        "Attempts of read-write transactions retried after spanner aborted them.",
+			ConstLabels: c.labels,
+		}, []string{"tx_mode", "tag"}),
+	}
+}
+
+// WithCollector records the metrics of the operations of a DB in c.
+func WithCollector(c *Collector) spansqlx.Option {
+	return spansqlx.WithInterceptors(c)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	c.rowsScanned.Describe(ch)
+	c.rowsAffected.Describe(ch)
+	c.mutations.Describe(ch)
+	c.transactions.Describe(ch)
+	c.retries.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+	c.rowsScanned.Collect(ch)
+	c.rowsAffected.Collect(ch)
+	c.mutations.Collect(ch)
+	c.transactions.Collect(ch)
+	c.retries.Collect(ch)
+}
+
+// Before implements spansqlx.Interceptor.
+func (c *Collector) Before(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+	return ctx, nil
+}
+
+// After implements spansqlx.Interceptor, it records op.
+func (c *Collector) After(ctx context.Context, op *spansqlx.Operation) {
+	status := "ok"
+	if op.Err != nil {
+		status = "error"
+	}
+	c.duration.WithLabelValues(string(op.Kind), string(op.TxMode), op.RequestTag, status).Observe(op.Duration.Seconds())
+
+	switch {
+	case op.Kind == spansqlx.OpTransaction:
+		// read-only transactions neither commit nor abort, their duration
+		// and status are enough.
+		if op.TxMode != spansqlx.TxReadWrite {
+			break
+		}
+		c.transactions.WithLabelValues(string(op.TxMode), op.RequestTag, transactionResult(op.Err)).Inc()
+		if op.Attempt > 1 {
+			c.retries.WithLabelValues(string(op.TxMode), op.RequestTag).Add(float64(op.Attempt - 1))
+		}
+	case op.Kind.Read():
+		c.rowsScanned.WithLabelValues(string(op.Kind), op.RequestTag).Add(float64(op.Rows))
+	case len(op.Statements) > 0:
+		c.rowsAffected.WithLabelValues(string(op.Kind), op.RequestTag).Add(float64(op.Rows))
+	case len(op.Mutations) > 0:
+		c.mutations.WithLabelValues(string(op.TxMode), op.RequestTag).Add(float64(len(op.Mutations)))
+	}
+}
+
+// transactionResult returns the result label of a read-write transaction
+// which ended with err: commit, abort when spanner aborted it more than
+// allowed by spansqlx.WithMaxAttempts, or error.
+func transactionResult(err error) string {
+	switch {
+	case err == nil:
+		return "commit"
+	case spansqlx.IsAborted(err):
+		return "abort"
+	default:
+		return "error"
+	}
+}
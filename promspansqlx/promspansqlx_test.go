@@ -0,0 +1,154 @@
+package promspansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal/spantest"
+	"github.com/reiot101/spansqlx/promspansqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestDB(t *testing.T, opts ...spansqlx.Option) *spansqlx.DB {
+	t.Helper()
+
+	client := spantest.NewClient(t, `CREATE TABLE Singers (
+	SingerID INT64 NOT NULL,
+	Name STRING(1024),
+) PRIMARY KEY (SingerID)`)
+
+	return spansqlx.NewDb(context.Background(), client, opts...)
+}
+
+// value returns the value of the counter, or the count of the histogram, of
+// name with labels.
+func value(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue metrics
+				}
+			}
+			return metricValue(m)
+		}
+	}
+	return 0
+}
+
+func metricValue(m *dto.Metric) float64 {
+	if h := m.GetHistogram(); h != nil {
+		return float64(h.GetSampleCount())
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestCollector(t *testing.T) {
+	metrics := promspansqlx.NewCollector(promspansqlx.WithNamespace("test"))
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	// aborts the transactions started while abort is set, after the
+	// collector saw them start.
+	var abort bool
+	db := newTestDB(t, promspansqlx.WithCollector(metrics), spansqlx.WithInterceptors(spansqlx.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+			if abort && op.Kind == spansqlx.OpTransaction {
+				return ctx, spanner.ToSpannerError(status.Error(codes.Aborted, "aborted"))
+			}
+			return ctx, nil
+		},
+	}))
+	ctx := spansqlx.SetRequestTag(context.Background(), "singers")
+
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		return db.InsertOrUpdate(ctx, "Singers", []map[string]interface{}{
+			{"SingerID": int64(1), "Name": "Marc"},
+			{"SingerID": int64(2), "Name": "Catalina"},
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if err := db.Select(ctx, &names, `SELECT Name FROM Singers`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(context.Background(), `UPDATE Singers SET Name = 'x' WHERE true`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE Missing SET Name = 'x' WHERE true`); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// a transaction which aborted, and one which failed.
+	abort = true
+	if err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		return nil
+	}); !spansqlx.IsAborted(err) {
+		t.Fatalf("got error %v, want aborted", err)
+	}
+	abort = false
+	// aborted by spanner more than allowed.
+	aborted := spanner.ToSpannerError(status.Error(codes.Aborted, "aborted"))
+	if err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		return aborted
+	}, spansqlx.WithMaxAttempts(2)); !errors.Is(err, spansqlx.ErrMaxAttempts) {
+		t.Fatalf("got error %v, want ErrMaxAttempts", err)
+	}
+	errFailed := errors.New("failed")
+	if err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		return errFailed
+	}); !errors.Is(err, errFailed) {
+		t.Fatalf("got error %v, want errFailed", err)
+	}
+	// read-only transactions are only timed.
+	if err := db.ReadOnlyPipeline(context.Background(), func(ctx context.Context) error {
+		return db.Select(ctx, &names, `SELECT Name FROM Singers`)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"test_spansqlx_transactions_total", map[string]string{"tx_mode": "read-write", "tag": "singers", "result": "commit"}, 1},
+		{"test_spansqlx_transactions_total", map[string]string{"tx_mode": "read-write", "tag": "singers", "result": "abort"}, 2},
+		{"test_spansqlx_transaction_retries_total", map[string]string{"tx_mode": "read-write", "tag": "singers"}, 1},
+		// the failed pipeline and the transaction of the failed Exec.
+		{"test_spansqlx_transactions_total", map[string]string{"tx_mode": "read-write", "tag": "singers", "result": "error"}, 2},
+		{"test_spansqlx_transactions_total", map[string]string{"tx_mode": "read-write", "tag": "", "result": "commit"}, 1},
+		{"test_spansqlx_transactions_total", map[string]string{"tx_mode": "read-only"}, 0},
+		{"test_spansqlx_operation_duration_seconds", map[string]string{"operation": "transaction", "tx_mode": "read-only", "status": "ok"}, 1},
+		{"test_spansqlx_mutations_total", map[string]string{"tx_mode": "read-write", "tag": "singers"}, 2},
+		{"test_spansqlx_rows_scanned_total", map[string]string{"operation": "query", "tag": "singers"}, 2},
+		{"test_spansqlx_rows_affected_total", map[string]string{"operation": "exec", "tag": ""}, 2},
+		{"test_spansqlx_operation_duration_seconds", map[string]string{"operation": "exec", "tag": "singers", "status": "error"}, 1},
+		{"test_spansqlx_operation_duration_seconds", map[string]string{"operation": "query", "tx_mode": "single", "status": "ok"}, 1},
+	} {
+		if got := value(t, reg, tt.name, tt.labels); got != tt.want {
+			t.Errorf("%s%v: got %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package spansqlx
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Querier reads from spanner. It is implemented by *DB, which reads from the
+// transaction of the context if any, and by *ReadOnlyTx and *ReadWriteTx,
+// which read from their own transaction.
+type Querier interface {
+	Get(ctx context.Context, dest interface{}, sql string, args ...interface{}) error
+	GetX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error
+	Select(ctx context.Context, dest interface{}, sql string, args ...interface{}) error
+	SelectX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error
+	Query(ctx context.Context, sql string, args ...interface{}) ([]*spanner.Row, error)
+	QueryX(ctx context.Context, stmt spanner.Statement, opts ...ReadOption) ([]*spanner.Row, error)
+	NamedGet(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error
+	NamedSelect(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error
+}
+
+// Execer writes to spanner with DML statements and mutations. It is
+// implemented by *DB and *ReadWriteTx.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (Result, error)
+	ExecX(ctx context.Context, stmt spanner.Statement, opts ...ExecOption) (Result, error)
+	NamedExec(ctx context.Context, sql string, arg interface{}, opts ...ExecOption) (Result, error)
+	Insert(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error
+	Update(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error
+	InsertOrUpdate(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error
+	Replace(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error
+	Delete(ctx context.Context, table string, keys spanner.KeySet) error
+	Apply(ctx context.Context, ms ...*spanner.Mutation) error
+}
+
+// QueryExecer is a Querier and an Execer, e.g. the handle of a repository
+// which may be a *DB or a *ReadWriteTx.
+type QueryExecer interface {
+	Querier
+	Execer
+}
+
+var (
+	_ QueryExecer = (*DB)(nil)
+	_ QueryExecer = (*ReadWriteTx)(nil)
+	_ Querier     = (*ReadOnlyTx)(nil)
+)
+
+// ReadOnlyTx is the read-only transaction of a ReadOnlyTxPipeline. Every
+// read is made from its snapshot, whichever the context it is given.
+type ReadOnlyTx struct {
+	d  *DB
+	tx *spanner.ReadOnlyTransaction
+}
+
+// ReadOnlyTxPipeline is ReadOnlyPipeline handing the transaction to the
+// callback, as a Querier which does not depend on the context.
+func (d *DB) ReadOnlyTxPipeline(ctx context.Context, callback func(ctx context.Context, tx *ReadOnlyTx) error, opts ...ReadOption) error {
+	return d.ReadOnlyPipeline(ctx, func(ctx context.Context) error {
+		tx, _ := hasReadOnlyTxContext(ctx)
+		return callback(ctx, &ReadOnlyTx{d: d, tx: tx})
+	}, opts...)
+}
+
+func (t *ReadOnlyTx) ctx(ctx context.Context) context.Context {
+	return SetTxContext(ctx, t.tx)
+}
+
+// ReadTimestamp returns the timestamp of the snapshot of the transaction.
+func (t *ReadOnlyTx) ReadTimestamp() (time.Time, error) {
+	return ReadTimestamp(t.ctx(context.Background()))
+}
+
+// Get is DB.Get within the transaction.
+func (t *ReadOnlyTx) Get(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
+	return t.d.Get(t.ctx(ctx), dest, sql, args...)
+}
+
+// GetX is DB.GetX within the transaction.
+func (t *ReadOnlyTx) GetX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return t.d.GetX(t.ctx(ctx), dest, stmt, opts...)
+}
+
+// Select is DB.Select within the transaction.
+func (t *ReadOnlyTx) Select(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
+	return t.d.Select(t.ctx(ctx), dest, sql, args...)
+}
+
+// SelectX is DB.SelectX within the transaction.
+func (t *ReadOnlyTx) SelectX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return t.d.SelectX(t.ctx(ctx), dest, stmt, opts...)
+}
+
+// Query is DB.Query within the transaction.
+func (t *ReadOnlyTx) Query(ctx context.Context, sql string, args ...interface{}) ([]*spanner.Row, error) {
+	return t.d.Query(t.ctx(ctx), sql, args...)
+}
+
+// QueryX is DB.QueryX within the transaction.
+func (t *ReadOnlyTx) QueryX(ctx context.Context, stmt spanner.Statement, opts ...ReadOption) ([]*spanner.Row, error) {
+	return t.d.QueryX(t.ctx(ctx), stmt, opts...)
+}
+
+// NamedGet is DB.NamedGet within the transaction.
+func (t *ReadOnlyTx) NamedGet(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	return t.d.NamedGet(t.ctx(ctx), dest, sql, arg, opts...)
+}
+
+// NamedSelect is DB.NamedSelect within the transaction.
+func (t *ReadOnlyTx) NamedSelect(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	return t.d.NamedSelect(t.ctx(ctx), dest, sql, arg, opts...)
+}
+
+// ReadWriteTx is the read-write transaction of a ReadWriteTxPipeline. Every
+// statement and mutation runs in it, whichever the context it is given.
+type ReadWriteTx struct {
+	d  *DB
+	tx *spanner.ReadWriteTransaction
+	// id, attempt and pipeline of the TxPipeline attempt, which the
+	// operations of the transaction belong to.
+	txID     string
+	attempt  int
+	pipeline *txPipeline
+}
+
+// ReadWriteTxPipeline is TxPipeline handing the transaction to the callback,
+// as a QueryExecer which does not depend on the context. The callback is run
+// again, with a new transaction, if spanner aborts the transaction.
+func (d *DB) ReadWriteTxPipeline(ctx context.Context, callback func(ctx context.Context, tx *ReadWriteTx) error, opts ...TxOption) error {
+	return d.TxPipeline(ctx, func(ctx context.Context) error {
+		tx, _ := hasReadWriteTxContext(ctx)
+		p, _ := ctx.Value(txPipelineContextKey).(*txPipeline)
+		return callback(ctx, &ReadWriteTx{d: d, tx: tx, txID: txIDContext(ctx), attempt: attemptContext(ctx), pipeline: p})
+	}, opts...)
+}
+
+func (t *ReadWriteTx) ctx(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, txIDContextKey, t.txID)
+	ctx = context.WithValue(ctx, attemptContextKey, t.attempt)
+	ctx = context.WithValue(ctx, txPipelineContextKey, t.pipeline)
+	return SetTxContext(ctx, t.tx)
+}
+
+// Get is DB.Get within the transaction.
+func (t *ReadWriteTx) Get(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
+	return t.d.Get(t.ctx(ctx), dest, sql, args...)
+}
+
+// GetX is DB.GetX within the transaction.
+func (t *ReadWriteTx) GetX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return t.d.GetX(t.ctx(ctx), dest, stmt, opts...)
+}
+
+// Select is DB.Select within the transaction.
+func (t *ReadWriteTx) Select(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
+	return t.d.Select(t.ctx(ctx), dest, sql, args...)
+}
+
+// SelectX is DB.SelectX within the transaction.
+func (t *ReadWriteTx) SelectX(ctx context.Context, dest interface{}, stmt spanner.Statement, opts ...ReadOption) error {
+	return t.d.SelectX(t.ctx(ctx), dest, stmt, opts...)
+}
+
+// Query is DB.Query within the transaction.
+func (t *ReadWriteTx) Query(ctx context.Context, sql string, args ...interface{}) ([]*spanner.Row, error) {
+	return t.d.Query(t.ctx(ctx), sql, args...)
+}
+
+// QueryX is DB.QueryX within the transaction.
+func (t *ReadWriteTx) QueryX(ctx context.Context, stmt spanner.Statement, opts ...ReadOption) ([]*spanner.Row, error) {
+	return t.d.QueryX(t.ctx(ctx), stmt, opts...)
+}
+
+// NamedGet is DB.NamedGet within the transaction.
+func (t *ReadWriteTx) NamedGet(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	return t.d.NamedGet(t.ctx(ctx), dest, sql, arg, opts...)
+}
+
+// NamedSelect is DB.NamedSelect within the transaction.
+func (t *ReadWriteTx) NamedSelect(ctx context.Context, dest interface{}, sql string, arg interface{}, opts ...ReadOption) error {
+	return t.d.NamedSelect(t.ctx(ctx), dest, sql, arg, opts...)
+}
+
+// Exec is DB.Exec within the transaction.
+func (t *ReadWriteTx) Exec(ctx context.Context, sql string, args ...interface{}) (Result, error) {
+	return t.d.Exec(t.ctx(ctx), sql, args...)
+}
+
+// ExecX is DB.ExecX within the transaction.
+func (t *ReadWriteTx) ExecX(ctx context.Context, stmt spanner.Statement, opts ...ExecOption) (Result, error) {
+	return t.d.ExecX(t.ctx(ctx), stmt, opts...)
+}
+
+// NamedExec is DB.NamedExec within the transaction.
+func (t *ReadWriteTx) NamedExec(ctx context.Context, sql string, arg interface{}, opts ...ExecOption) (Result, error) {
+	return t.d.NamedExec(t.ctx(ctx), sql, arg, opts...)
+}
+
+// ExecBatch is DB.ExecBatch within the transaction.
+func (t *ReadWriteTx) ExecBatch(ctx context.Context, stmts ...spanner.Statement) ([]int64, error) {
+	return t.d.ExecBatch(t.ctx(ctx), stmts...)
+}
+
+// NamedExecBatch is DB.NamedExecBatch within the transaction.
+func (t *ReadWriteTx) NamedExecBatch(ctx context.Context, sql string, arg interface{}) ([]int64, error) {
+	return t.d.NamedExecBatch(t.ctx(ctx), sql, arg)
+}
+
+// Insert is DB.Insert buffered in the transaction.
+func (t *ReadWriteTx) Insert(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return t.d.Insert(t.ctx(ctx), table, arg, opts...)
+}
+
+// Update is DB.Update buffered in the transaction.
+func (t *ReadWriteTx) Update(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return t.d.Update(t.ctx(ctx), table, arg, opts...)
+}
+
+// InsertOrUpdate is DB.InsertOrUpdate buffered in the transaction.
+func (t *ReadWriteTx) InsertOrUpdate(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return t.d.InsertOrUpdate(t.ctx(ctx), table, arg, opts...)
+}
+
+// Replace is DB.Replace buffered in the transaction.
+func (t *ReadWriteTx) Replace(ctx context.Context, table string, arg interface{}, opts ...MutationOption) error {
+	return t.d.Replace(t.ctx(ctx), table, arg, opts...)
+}
+
+// Delete is DB.Delete buffered in the transaction.
+func (t *ReadWriteTx) Delete(ctx context.Context, table string, keys spanner.KeySet) error {
+	return t.d.Delete(t.ctx(ctx), table, keys)
+}
+
+// Apply is DB.Apply buffered in the transaction.
+func (t *ReadWriteTx) Apply(ctx context.Context, ms ...*spanner.Mutation) error {
+	return t.d.Apply(t.ctx(ctx), ms...)
+}
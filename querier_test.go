@@ -0,0 +1,85 @@
+package spansqlx_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+// renameSinger is written once against spansqlx.QueryExecer.
+func renameSinger(ctx context.Context, q spansqlx.QueryExecer, id int64, lastName string) (Singer, error) {
+	if _, err := q.Exec(ctx, `UPDATE Singers SET LastName = @last WHERE SingerID = @id`, lastName, id, spansqlx.ExpectRows(1)); err != nil {
+		return Singer{}, err
+	}
+	var s Singer
+	err := q.Get(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, id)
+	return s, err
+}
+
+func TestQuerier(t *testing.T) {
+	_, client := newTestDB(t)
+	ctx := context.Background()
+
+	var (
+		ops       []spansqlx.Operation
+		committed int
+	)
+	db := spansqlx.NewDb(ctx, client, spansqlx.WithInterceptors(spansqlx.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, op *spansqlx.Operation) (context.Context, error) {
+			if op.Kind != spansqlx.OpApply || op.TxMode != spansqlx.TxReadWrite {
+				return ctx, nil
+			}
+			return ctx, spansqlx.AfterCommit(ctx, func(context.Context) { committed++ })
+		},
+		AfterFunc: func(ctx context.Context, op *spansqlx.Operation) {
+			ops = append(ops, *op)
+		},
+	}))
+
+	if s, err := renameSinger(ctx, db, 1, "Pool"); err != nil || s.LastName != "Pool" {
+		t.Fatalf("got %+v, error %v", s, err)
+	}
+
+	err := db.ReadWriteTxPipeline(ctx, func(_ context.Context, tx *spansqlx.ReadWriteTx) error {
+		// the transaction does not depend on the context it is given.
+		s, err := renameSinger(context.Background(), tx, 1, "Tx")
+		if err != nil {
+			return err
+		}
+		if s.LastName != "Tx" {
+			return fmt.Errorf("got %+v", s)
+		}
+		return tx.Insert(context.Background(), "Albums", Album{SingerID: 1, AlbumID: 10, AlbumTitle: "Buffered"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// its operations belong to the pipeline all the same.
+	pipeline := ops[len(ops)-1]
+	if pipeline.Kind != spansqlx.OpTransaction || committed != 1 {
+		t.Fatalf("got pipeline %+v, %d after commit calls", pipeline, committed)
+	}
+	for _, op := range ops[len(ops)-4 : len(ops)-1] {
+		if op.TxID != pipeline.TxID || op.Attempt != 1 {
+			t.Fatalf("got operation %+v of pipeline %s", op, pipeline.TxID)
+		}
+	}
+
+	err = db.ReadOnlyTxPipeline(ctx, func(_ context.Context, tx *spansqlx.ReadOnlyTx) error {
+		var q spansqlx.Querier = tx
+		var title string
+		if err := q.Get(context.Background(), &title, `SELECT AlbumTitle FROM Albums WHERE SingerID = 1 AND AlbumID = 10`); err != nil {
+			return err
+		}
+		if title != "Buffered" {
+			return fmt.Errorf("got title %q", title)
+		}
+		_, err := tx.ReadTimestamp()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package spansqlx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/iterator"
+)
+
+// ErrBoundedStaleness is returned when a read-only transaction, or the
+// snapshot of a partitioned query, is given a bounded staleness, which
+// spanner only accepts on single reads.
+var ErrBoundedStaleness = errors.New("spansqlx: bounded staleness is only valid on single reads")
+
+// ReadOption sets the timestamp bound of reads.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	bound *spanner.TimestampBound
+	// bounded staleness, which only single-use reads accept.
+	bounded bool
+}
+
+func newReadOptions(opts ...ReadOption) readOptions {
+	var o readOptions
+	for i := range opts {
+		opts[i](&o)
+	}
+	return o
+}
+
+// multiUseBound returns the timestamp bound of a multi-use read-only
+// transaction, a strong read by default. Bounded staleness fails with
+// ErrBoundedStaleness.
+func (o readOptions) multiUseBound() (spanner.TimestampBound, error) {
+	switch {
+	case o.bound == nil:
+		return spanner.StrongRead(), nil
+	case o.bounded:
+		return spanner.TimestampBound{}, ErrBoundedStaleness
+	default:
+		return *o.bound, nil
+	}
+}
+
+func withBound(tb spanner.TimestampBound, bounded bool) ReadOption {
+	return func(o *readOptions) {
+		o.bound = &tb
+		o.bounded = bounded
+	}
+}
+
+// WithStrongRead reads at a timestamp where all previously committed
+// transactions are visible. It is the default.
+func WithStrongRead() ReadOption {
+	return withBound(spanner.StrongRead(), false)
+}
+
+// WithExactStaleness reads at a timestamp exactly d old.
+func WithExactStaleness(d time.Duration) ReadOption {
+	return withBound(spanner.ExactStaleness(d), false)
+}
+
+// WithReadTimestamp reads at the exact timestamp t.
+func WithReadTimestamp(t time.Time) ReadOption {
+	return withBound(spanner.ReadTimestamp(t), false)
+}
+
+// WithStaleness is a shorthand of WithMaxStaleness, for single reads which
+// tolerate data up to d old.
+func WithStaleness(d time.Duration) ReadOption {
+	return WithMaxStaleness(d)
+}
+
+// WithMaxStaleness reads at a timestamp at most d old, chosen by spanner.
+// Spanner only accepts bounded staleness on single reads, a ReadOnlyPipeline
+// given it fails with ErrBoundedStaleness.
+func WithMaxStaleness(d time.Duration) ReadOption {
+	return withBound(spanner.MaxStaleness(d), true)
+}
+
+// WithMinReadTimestamp reads at a timestamp not older than t, chosen by
+// spanner. Spanner only accepts bounded staleness on single reads, a
+// ReadOnlyPipeline given it fails with ErrBoundedStaleness.
+func WithMinReadTimestamp(t time.Time) ReadOption {
+	return withBound(spanner.MinReadTimestamp(t), true)
+}
+
+// ReadOnlyPipeline is ReadOnlyTransaction wrap.
+// Every Get, Select and Query of the callback reads from the same snapshot,
+// as set by opts, the default being a strong read.
+func (d *DB) ReadOnlyPipeline(ctx context.Context, callback func(ctx context.Context) error, opts ...ReadOption) error {
+	tb, err := newReadOptions(opts...).multiUseBound()
+	if err != nil {
+		return err
+	}
+
+	ctx, op, err := d.startOperation(ctx, OpTransaction, TxReadOnly, nil, nil)
+	if err == nil {
+		tx := d.db.ReadOnlyTransaction().WithTimestampBound(tb)
+		defer tx.Close()
+
+		err = callback(SetTxContext(ctx, tx))
+	}
+	err = d.endOperation(ctx, op, 0, err)
+	return err
+}
+
+// ReadTimestamp returns the timestamp of the snapshot read by the
+// ReadOnlyPipeline of ctx. If nothing was read yet, the transaction is
+// started to choose the timestamp.
+func ReadTimestamp(ctx context.Context) (time.Time, error) {
+	tx, ok := hasReadOnlyTxContext(ctx)
+	if !ok {
+		return time.Time{}, errors.New("spansqlx: no read-only transaction in context")
+	}
+
+	if ts, err := tx.Timestamp(); err == nil {
+		return ts, nil
+	}
+
+	iter := tx.Query(ctx, spanner.NewStatement("SELECT 1"))
+	defer iter.Stop()
+	if _, err := iter.Next(); err != nil && err != iterator.Done {
+		return time.Time{}, err
+	}
+
+	return tx.Timestamp()
+}
+
+// SetReadContext attaches opts to ctx, they apply to every single read made
+// with ctx unless overridden by the options of a call.
+func SetReadContext(ctx context.Context, opts ...ReadOption) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, readOptionsContextKey, append(readOptionsContext(ctx), opts...))
+}
+
+// readOptionsContext returns the read options attached to ctx.
+func readOptionsContext(ctx context.Context) []ReadOption {
+	if ctx == nil {
+		return nil
+	}
+	opts, _ := ctx.Value(readOptionsContextKey).([]ReadOption)
+	return opts[:len(opts):len(opts)]
+}
+
+// splitArgs separates the ReadOption values of args from the query args.
+func splitArgs(args []interface{}) ([]interface{}, []ReadOption) {
+	var opts []ReadOption
+	for _, arg := range args {
+		if o, ok := arg.(ReadOption); ok {
+			opts = append(opts, o)
+		}
+	}
+	if len(opts) == 0 {
+		return args, nil
+	}
+
+	rest := make([]interface{}, 0, len(args)-len(opts))
+	for _, arg := range args {
+		if _, ok := arg.(ReadOption); !ok {
+			rest = append(rest, arg)
+		}
+	}
+	return rest, opts
+}
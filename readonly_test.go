@@ -0,0 +1,77 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestReadOnlyPipeline(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	before := time.Now()
+	err := db.ReadOnlyPipeline(context.Background(), func(ctx context.Context) error {
+		ts, err := spansqlx.ReadTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		if ts.IsZero() || ts.After(time.Now()) {
+			t.Errorf("unexpected read timestamp %v (started at %v)", ts, before)
+		}
+
+		var n int64
+		if err := db.Get(ctx, &n, `SELECT COUNT(*) FROM Singers`); err != nil {
+			return err
+		}
+		if n != int64(len(allSingers)) {
+			t.Errorf("got %d singers, want %d", n, len(allSingers))
+		}
+		return nil
+	}, spansqlx.WithExactStaleness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bounded staleness is only valid on single reads.
+	for _, o := range []spansqlx.ReadOption{spansqlx.WithMaxStaleness(time.Minute), spansqlx.WithMinReadTimestamp(before)} {
+		err := db.ReadOnlyPipeline(context.Background(), func(ctx context.Context) error {
+			t.Fatal("pipeline run with bounded staleness")
+			return nil
+		}, o)
+		if !errors.Is(err, spansqlx.ErrBoundedStaleness) {
+			t.Fatalf("got error %v, want ErrBoundedStaleness", err)
+		}
+	}
+
+	if _, err := spansqlx.ReadTimestamp(context.Background()); err == nil {
+		t.Fatal("expected error without a read-only transaction")
+	}
+}
+
+func TestReadOptions(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	ctx := spansqlx.SetReadContext(context.Background(), spansqlx.WithExactStaleness(0))
+
+	var s Singer
+	if err := db.Get(ctx, &s, `SELECT SingerID, FirstName, LastName FROM Singers WHERE SingerID = @id`,
+		spansqlx.WithStaleness(15*time.Second), 3); err != nil {
+		t.Fatal(err)
+	}
+	if s != allSingers[2] {
+		t.Fatalf("got %+v, want %+v", s, allSingers[2])
+	}
+
+	var albums []Album
+	if err := db.SelectX(ctx, &albums, spanner.NewStatement(`SELECT SingerID, AlbumID, AlbumTitle FROM Albums`),
+		spansqlx.WithReadTimestamp(time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != len(allAlbums) {
+		t.Fatalf("got %d albums, want %d", len(albums), len(allAlbums))
+	}
+}
@@ -2,9 +2,339 @@ package reflectx
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
 )
 
+// NameMapper maps a Go struct field name to a column or parameter name. It
+// is only used for fields that do not carry an explicit name in a tag.
+type NameMapper func(string) string
+
+// Identity keeps the Go field name as is.
+func Identity(s string) string { return s }
+
+// LowerCase maps FirstName to firstname.
+func LowerCase(s string) string { return strings.ToLower(s) }
+
+// SnakeCase maps FirstName to first_name and SingerID to singer_id.
+func SnakeCase(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// FieldInfo is the mapping metadata of a single struct field.
+type FieldInfo struct {
+	// Index is the index sequence for reflect.Value.FieldByIndex.
+	Index []int
+	// Name is the column or parameter name of the field. Fields of nested
+	// structs are prefixed with the name of the parent field and a dot,
+	// e.g. owner.name, fields of embedded structs are promoted as is.
+	Name string
+	// Path is the Go selector of the field, e.g. Owner.Name.
+	Path string
+	// Options are the comma separated tag options, e.g. `db:"name,omitempty"`.
+	Options map[string]string
+	Field   reflect.StructField
+}
+
+// StructMap is the field mapping of a struct type.
+type StructMap struct {
+	// Index lists the fields in declaration order.
+	Index []*FieldInfo
+	// Names maps each column name to its field.
+	Names map[string]*FieldInfo
+	// Ambiguous lists the names which more than one field at the same
+	// depth maps to. Those names are missing from Index and Names.
+	Ambiguous map[string][]*FieldInfo
+
+	folded map[string]*FieldInfo
+}
+
+// GetByName returns the field mapped to name. Spanner column names are case
+// insensitive, so an exact match is preferred and a case folded match is
+// used otherwise. It returns nil if no field is mapped to name.
+func (s *StructMap) GetByName(name string) *FieldInfo {
+	if fi, ok := s.Names[name]; ok {
+		return fi
+	}
+	return s.folded[strings.ToLower(name)]
+}
+
+// Lookup is like GetByName, but returns an *AmbiguousError if name is
+// ambiguous in the struct.
+func (s *StructMap) Lookup(name string) (*FieldInfo, error) {
+	if fi := s.GetByName(name); fi != nil {
+		return fi, nil
+	}
+	for n, fis := range s.Ambiguous {
+		if strings.EqualFold(n, name) {
+			return nil, &AmbiguousError{Name: n, Fields: fis}
+		}
+	}
+	return nil, nil
+}
+
+// AmbiguousError is returned when a name is mapped to several fields at the
+// same depth of a struct.
+type AmbiguousError struct {
+	Name   string
+	Fields []*FieldInfo
+}
+
+func (e *AmbiguousError) Error() string {
+	paths := make([]string, len(e.Fields))
+	for i, fi := range e.Fields {
+		paths[i] = fi.Path
+	}
+	return fmt.Sprintf("reflectx: ambiguous name %q maps to fields %s", e.Name, strings.Join(paths, ", "))
+}
+
+// Mapper is a general purpose mapper of names to struct fields. A Mapper
+// behaves like most marshallers in the standard library, obeying a field tag
+// for name mapping but also providing a NameMapper for fields without a tag.
+// Struct field maps are cached per type, so a Mapper is safe for concurrent
+// use and should be shared.
+type Mapper struct {
+	tagNames []string
+	mapFunc  NameMapper
+
+	mu    sync.Mutex
+	cache map[reflect.Type]*StructMap
+}
+
+// NewMapper returns a new mapper using the tagNames in order of precedence,
+// and the Go field name for untagged fields.
+func NewMapper(tagNames ...string) *Mapper {
+	return NewMapperFunc(Identity, tagNames...)
+}
+
+// NewMapperFunc returns a new mapper using the tagNames in order of
+// precedence, and mapFunc for untagged fields.
+func NewMapperFunc(mapFunc NameMapper, tagNames ...string) *Mapper {
+	if mapFunc == nil {
+		mapFunc = Identity
+	}
+	return &Mapper{
+		tagNames: tagNames,
+		mapFunc:  mapFunc,
+		cache:    make(map[reflect.Type]*StructMap),
+	}
+}
+
+// TypeMap returns the mapping of field names to fields for t, which must be
+// a struct or a pointer to a struct. The result is cached.
+func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
+	t = Deref(t)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sm, ok := m.cache[t]
+	if !ok {
+		sm = m.getMapping(t)
+		m.cache[t] = sm
+	}
+	return sm
+}
+
+// FieldMap returns the mapping of field names to reflect values of v, which
+// must be a struct or a pointer to a struct.
+func (m *Mapper) FieldMap(v reflect.Value) map[string]reflect.Value {
+	v = reflect.Indirect(v)
+	mustBe(v, reflect.Struct)
+
+	r := map[string]reflect.Value{}
+	for _, fi := range m.TypeMap(v.Type()).Index {
+		r[fi.Name] = FieldByIndexes(v, fi.Index)
+	}
+	return r
+}
+
+// FieldByName returns the field of v mapped to name, or the zero Value if no
+// field is mapped to it.
+func (m *Mapper) FieldByName(v reflect.Value, name string) reflect.Value {
+	v = reflect.Indirect(v)
+	mustBe(v, reflect.Struct)
+
+	fi := m.TypeMap(v.Type()).GetByName(name)
+	if fi == nil {
+		return reflect.Value{}
+	}
+	return FieldByIndexes(v, fi.Index)
+}
+
+// getMapping builds the StructMap of the struct type t.
+func (m *Mapper) getMapping(t reflect.Type) *StructMap {
+	var (
+		fields []*FieldInfo
+		depths = make(map[*FieldInfo]int)
+	)
+
+	m.walk(t, nil, "", "", 0, map[reflect.Type]bool{}, func(fi *FieldInfo, depth int) {
+		fields = append(fields, fi)
+		depths[fi] = depth
+	})
+
+	// as with Go field promotion, the shallowest field of a name wins, and
+	// several fields at the shallowest depth make the name ambiguous.
+	byName := make(map[string][]*FieldInfo)
+	for _, fi := range fields {
+		switch prev := byName[fi.Name]; {
+		case len(prev) == 0 || depths[fi] < depths[prev[0]]:
+			byName[fi.Name] = []*FieldInfo{fi}
+		case depths[fi] == depths[prev[0]]:
+			byName[fi.Name] = append(prev, fi)
+		}
+	}
+
+	sm := &StructMap{
+		Names:     make(map[string]*FieldInfo),
+		Ambiguous: make(map[string][]*FieldInfo),
+		folded:    make(map[string]*FieldInfo),
+	}
+
+	for _, fi := range fields {
+		winners := byName[fi.Name]
+		if len(winners) > 1 {
+			sm.Ambiguous[fi.Name] = winners
+			continue
+		}
+		if winners[0] != fi {
+			continue
+		}
+
+		sm.Index = append(sm.Index, fi)
+		sm.Names[fi.Name] = fi
+		if _, ok := sm.folded[strings.ToLower(fi.Name)]; !ok {
+			sm.folded[strings.ToLower(fi.Name)] = fi
+		}
+	}
+
+	return sm
+}
+
+// walk calls add for every mapped leaf field of the struct type t, entering
+// embedded and nested structs. visited guards against recursive types.
+func (m *Mapper) walk(t reflect.Type, index []int, prefix, path string, depth int, visited map[reflect.Type]bool, add func(*FieldInfo, int)) {
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := Deref(f.Type)
+
+		// skip unexported fields, but keep embedded structs of an unexported
+		// type as their exported fields are promoted.
+		if f.PkgPath != "" && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name, options, tagged := m.parseTag(f)
+		if name == "-" {
+			continue
+		}
+
+		fi := &FieldInfo{
+			Index:   append(append([]int{}, index...), i),
+			Name:    prefix + name,
+			Path:    path + f.Name,
+			Options: options,
+			Field:   f,
+		}
+
+		if ft.Kind() == reflect.Struct && !IsScannable(ft) {
+			if visited[ft] {
+				continue
+			}
+			if f.Anonymous && !tagged {
+				// embedded struct, promote its fields.
+				m.walk(ft, fi.Index, prefix, fi.Path+".", depth+1, visited, add)
+			} else {
+				// nested struct, prefix its fields.
+				m.walk(ft, fi.Index, fi.Name+".", fi.Path+".", depth+1, visited, add)
+			}
+			continue
+		}
+
+		add(fi, depth)
+	}
+}
+
+// parseTag returns the mapped name and the tag options of f, and whether
+// the name was given by a tag.
+func (m *Mapper) parseTag(f reflect.StructField) (string, map[string]string, bool) {
+	for _, tagName := range m.tagNames {
+		tag, ok := f.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		options := make(map[string]string)
+		for _, opt := range parts[1:] {
+			kv := strings.SplitN(opt, "=", 2)
+			if len(kv) == 2 {
+				options[kv[0]] = kv[1]
+			} else {
+				options[kv[0]] = ""
+			}
+		}
+
+		name := parts[0]
+		if name == "" {
+			return m.mapFunc(f.Name), options, false
+		}
+		return name, options, true
+	}
+
+	return m.mapFunc(f.Name), nil, false
+}
+
+// FieldByIndexes returns the nested field of v at indexes, allocating any
+// nil pointer to struct along the way.
+func FieldByIndexes(v reflect.Value, indexes []int) reflect.Value {
+	for n, i := range indexes {
+		v = reflect.Indirect(v).Field(i)
+		if n < len(indexes)-1 && v.Kind() == reflect.Ptr && v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+	}
+	return v
+}
+
+// FieldByIndexesReadOnly returns the nested field of v at indexes, or the
+// zero Value if a nil pointer is found along the way.
+func FieldByIndexesReadOnly(v reflect.Value, indexes []int) reflect.Value {
+	for _, i := range indexes {
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return v
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
 // Deref is Indirect for reflect.Types
 func Deref(t reflect.Type) reflect.Type {
 	if t.Kind() == reflect.Ptr {
@@ -20,3 +350,33 @@ func BaseType(t reflect.Type, expected reflect.Kind) (reflect.Type, error) {
 	}
 	return t, nil
 }
+
+var (
+	decoderType  = reflect.TypeOf((*spanner.Decoder)(nil)).Elem()
+	encoderType  = reflect.TypeOf((*spanner.Encoder)(nil)).Elem()
+	nullableType = reflect.TypeOf((*spanner.NullableValue)(nil)).Elem()
+)
+
+// IsScannable reports whether a single column can be decoded directly into a
+// value of type t, rather than t being a struct of one field per column.
+// Such struct types are never entered by a Mapper.
+func IsScannable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	if reflect.PtrTo(t).Implements(decoderType) || t.Implements(encoderType) || t.Implements(nullableType) {
+		return true
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(civil.Date{}),
+		reflect.TypeOf(big.Rat{}), reflect.TypeOf(spanner.GenericColumnValue{}):
+		return true
+	}
+	return false
+}
+
+func mustBe(v reflect.Value, expected reflect.Kind) {
+	if k := v.Kind(); k != expected {
+		panic(&reflect.ValueError{Method: "reflectx.mustBe", Kind: k})
+	}
+}
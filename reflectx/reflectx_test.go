@@ -0,0 +1,126 @@
+package reflectx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"FirstName":  "first_name",
+		"SingerID":   "singer_id",
+		"HTTPServer": "http_server",
+		"Album2Name": "album2_name",
+		"id":         "id",
+		"":           "",
+	} {
+		if got := SnakeCase(in); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMapper(t *testing.T) {
+	type Singer struct {
+		SingerID  int64  `spanner:"id" db:"singer_id"`
+		FirstName string `db:"first"`
+		LastName  string
+		Ignored   string `spanner:"-"`
+		private   string
+	}
+
+	m := NewMapperFunc(SnakeCase, "spanner", "db")
+	sm := m.TypeMap(reflect.TypeOf(&Singer{}))
+
+	var names []string
+	for _, fi := range sm.Index {
+		names = append(names, fi.Name)
+	}
+	if want := []string{"id", "first", "last_name"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got names %v, want %v", names, want)
+	}
+
+	if fi := sm.GetByName("LAST_NAME"); fi == nil || fi.Field.Name != "LastName" {
+		t.Fatalf("case folded lookup failed: %+v", fi)
+	}
+	if sm != m.TypeMap(reflect.TypeOf(Singer{})) {
+		t.Fatal("expected cached struct map")
+	}
+
+	s := Singer{SingerID: 7}
+	if v := m.FieldByName(reflect.ValueOf(&s), "id"); v.Int() != 7 {
+		t.Fatalf("FieldByName(id) = %v", v)
+	}
+	m.FieldMap(reflect.ValueOf(&s))["first"].SetString("Marc")
+	if s.FirstName != "Marc" {
+		t.Fatalf("FieldMap did not address the struct field: %+v", s)
+	}
+}
+
+func TestMapperEmbedded(t *testing.T) {
+	type Audit struct {
+		CreatedAt int64
+		UpdatedAt int64
+	}
+	type tenantScoped struct {
+		TenantID string
+	}
+	type Person struct {
+		Name string `db:"name"`
+	}
+	type Album struct {
+		Audit
+		tenantScoped
+		AlbumID   int64
+		CreatedAt int64   // shadows Audit.CreatedAt
+		Owner     *Person `db:"owner"`
+		Singer    Person
+		Label     string `db:"label.name"`
+	}
+
+	m := NewMapper("db")
+	sm := m.TypeMap(reflect.TypeOf(Album{}))
+
+	var names []string
+	for _, fi := range sm.Index {
+		names = append(names, fi.Name)
+	}
+	want := []string{"UpdatedAt", "TenantID", "AlbumID", "CreatedAt", "owner.name", "Singer.name", "label.name"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got names %v, want %v", names, want)
+	}
+	if fi := sm.GetByName("CreatedAt"); !reflect.DeepEqual(fi.Index, []int{3}) {
+		t.Fatalf("CreatedAt should map to the outer field, got %v", fi.Index)
+	}
+
+	var a Album
+	m.FieldByName(reflect.ValueOf(&a), "owner.name").SetString("Marc")
+	if a.Owner == nil || a.Owner.Name != "Marc" {
+		t.Fatalf("nested pointer was not allocated: %+v", a.Owner)
+	}
+	if v := FieldByIndexesReadOnly(reflect.ValueOf(Album{}), sm.GetByName("owner.name").Index); v.IsValid() {
+		t.Fatal("expected invalid value through nil pointer")
+	}
+}
+
+func TestMapperAmbiguous(t *testing.T) {
+	type A struct{ ID, Name string }
+	type B struct{ ID string }
+	type C struct {
+		A
+		B
+	}
+
+	sm := NewMapper("db").TypeMap(reflect.TypeOf(C{}))
+	if sm.GetByName("ID") != nil {
+		t.Fatal("ambiguous name must not be mapped")
+	}
+	if fi, _ := sm.Lookup("Name"); fi == nil {
+		t.Fatal("expected Name to be mapped")
+	}
+
+	_, err := sm.Lookup("id")
+	if err == nil || err.Error() != `reflectx: ambiguous name "ID" maps to fields A.ID, B.ID` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
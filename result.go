@@ -0,0 +1,72 @@
+package spansqlx
+
+import "fmt"
+
+// Result of a DML statement.
+type Result struct {
+	// RowsAffected is the number of rows inserted, updated or deleted.
+	RowsAffected int64
+}
+
+// ExecOption checks the result of Exec, ExecX and NamedExec.
+type ExecOption func(*execOptions)
+
+type execOptions struct {
+	expectRows *int64
+}
+
+func newExecOptions(opts ...ExecOption) execOptions {
+	var o execOptions
+	for i := range opts {
+		opts[i](&o)
+	}
+	return o
+}
+
+// ExpectRows fails the statement with an *Error wrapping a
+// *RowsAffectedError unless it affects exactly n rows, e.g. an optimistic
+// update guarded by a version column:
+//
+//	_, err := db.Exec(ctx, `UPDATE Todos SET Title = @title, Version = Version + 1
+//		WHERE ID = @id AND Version = @version`, title, id, version, spansqlx.ExpectRows(1))
+//
+// The transaction of the statement is rolled back, so is a TxPipeline whose
+// callback returns the error.
+func ExpectRows(n int64) ExecOption {
+	return func(o *execOptions) {
+		o.expectRows = &n
+	}
+}
+
+// RowsAffectedError is returned when a statement does not affect the number
+// of rows set by ExpectRows.
+type RowsAffectedError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *RowsAffectedError) Error() string {
+	return fmt.Sprintf("spansqlx: expected %d affected rows, got %d", e.Expected, e.Actual)
+}
+
+// splitExecArgs separates the ExecOption values of args from the statement
+// args.
+func splitExecArgs(args []interface{}) ([]interface{}, []ExecOption) {
+	var opts []ExecOption
+	for _, arg := range args {
+		if o, ok := arg.(ExecOption); ok {
+			opts = append(opts, o)
+		}
+	}
+	if len(opts) == 0 {
+		return args, nil
+	}
+
+	rest := make([]interface{}, 0, len(args)-len(opts))
+	for _, arg := range args {
+		if _, ok := arg.(ExecOption); !ok {
+			rest = append(rest, arg)
+		}
+	}
+	return rest, opts
+}
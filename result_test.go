@@ -0,0 +1,53 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+)
+
+func TestExecResult(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	res, err := db.Exec(ctx, `UPDATE Albums SET AlbumTitle = @title WHERE SingerID = @id`, "Exec", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RowsAffected != 3 {
+		t.Fatalf("got %d affected rows, want 3", res.RowsAffected)
+	}
+
+	res, err = db.NamedExec(ctx, `UPDATE Singers SET LastName = @LastName WHERE SingerID = @SingerID`,
+		Singer{SingerID: 1, LastName: "Named"}, spansqlx.ExpectRows(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RowsAffected != 1 {
+		t.Fatalf("got %d affected rows, want 1", res.RowsAffected)
+	}
+
+	// the unexpected count fails the pipeline, spannertest does not roll
+	// back DML though.
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		_, err := db.Exec(ctx, `UPDATE Singers SET LastName = 'Missing' WHERE SingerID = @id`, 99, spansqlx.ExpectRows(1))
+		return err
+	})
+	var rowsErr *spansqlx.RowsAffectedError
+	if !errors.As(err, &rowsErr) || rowsErr.Expected != 1 || rowsErr.Actual != 0 {
+		t.Fatalf("got error %v", err)
+	}
+	// the mismatch is an error of the statement.
+	var e *spansqlx.Error
+	if !errors.As(err, &e) || e.Op != spansqlx.OpExec || e.Table != "Singers" {
+		t.Fatalf("got error %#v", err)
+	}
+
+	// errors of the transaction are returned
+	if _, err := db.ExecX(ctx, spanner.NewStatement(`UPDATE Missing SET Name = 'x' WHERE true`)); err == nil {
+		t.Fatal("expected error")
+	}
+}
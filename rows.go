@@ -0,0 +1,167 @@
+package spansqlx
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx/internal"
+	"github.com/reiot101/spansqlx/reflectx"
+	"google.golang.org/api/iterator"
+)
+
+// Rows is a cursor over the result of a query. Unlike Query, rows are
+// fetched from spanner lazily as Next is called, so a result set is never
+// buffered in memory as a whole.
+//
+//	rows, err := db.QueryRows(ctx, "SELECT * FROM Singers")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		var s Singer
+//		if err := rows.StructScan(&s); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+type Rows struct {
+	mapper *reflectx.Mapper
+	iter   *rowIter
+	row    *spanner.Row
+	err    error
+	closed bool
+}
+
+// QueryRows queries the database and returns a *Rows cursor.
+// Any placeholder parameters are replaced with supplied args, ReadOption
+// values among args set the timestamp bound of the read.
+// The caller must Close the returned Rows.
+func (d *DB) QueryRows(ctx context.Context, sql string, args ...interface{}) (*Rows, error) {
+	args, opts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return d.QueryRowsX(ctx, stmt, opts...)
+}
+
+// QueryRowsX queries the database and returns a *Rows cursor.
+// Based spanner statement.
+// The caller must Close the returned Rows.
+func (d *DB) QueryRowsX(ctx context.Context, stmt spanner.Statement, opts ...ReadOption) (*Rows, error) {
+	return &Rows{mapper: d.opts.mapper, iter: query(ctx, d, stmt, opts...)}, nil
+}
+
+// Next prepares the next row for reading with Scan or StructScan.
+// It returns false when the result set is exhausted or an error occurred,
+// Err should be consulted to distinguish between the two cases.
+func (r *Rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+
+	row, err := r.iter.Next()
+	if err != nil {
+		if err != iterator.Done {
+			r.err = err
+		}
+		r.row = nil
+		r.Close()
+		return false
+	}
+
+	r.row = row
+	return true
+}
+
+// Row returns the current *spanner.Row, or nil if Next has not been called
+// or returned false.
+func (r *Rows) Row() *spanner.Row {
+	return r.row
+}
+
+// Columns returns the column names of the current row, or of the result set
+// once its metadata was read, even if it has no rows.
+func (r *Rows) Columns() []string {
+	if r.row != nil {
+		return r.row.ColumnNames()
+	}
+	if r.iter.iter == nil || r.iter.iter.Metadata == nil {
+		return nil
+	}
+	fields := r.iter.iter.Metadata.GetRowType().GetFields()
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.GetName()
+	}
+	return names
+}
+
+// Scan the columns of the current row into dest, in order.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.row == nil {
+		return ErrNoRows
+	}
+	return r.row.Columns(dest...)
+}
+
+// StructScan the current row into dest, which may be a struct or a single
+// column base type.
+func (r *Rows) StructScan(dest interface{}) error {
+	if r.row == nil {
+		return ErrNoRows
+	}
+	return internal.ScanAny(r.mapper, r.row, dest)
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close stops the underlying iterator. It is safe to call Close multiple
+// times, and it is called automatically once Next returns false.
+func (r *Rows) Close() error {
+	if !r.closed {
+		r.closed = true
+		r.iter.Stop()
+	}
+	return nil
+}
+
+// SelectEach queries the database and calls fn with each row scanned into a
+// new T, one row at a time. Any placeholder parameters are replaced with
+// supplied args, ReadOption values among args set the timestamp bound of the
+// read. Iteration stops at the first error returned by fn.
+func SelectEach[T any](ctx context.Context, d *DB, sql string, args []interface{}, fn func(*T) error) error {
+	args, opts := splitArgs(args)
+
+	stmt, err := internal.PrepareStmtAll(sql, args...)
+	if err != nil {
+		return err
+	}
+	return SelectEachX(ctx, d, stmt, fn, opts...)
+}
+
+// SelectEachX is SelectEach based spanner statement.
+func SelectEachX[T any](ctx context.Context, d *DB, stmt spanner.Statement, fn func(*T) error, opts ...ReadOption) error {
+	rows, err := d.QueryRowsX(ctx, stmt, opts...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := new(T)
+		if err := rows.StructScan(v); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
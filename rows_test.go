@@ -0,0 +1,72 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reiot101/spansqlx"
+)
+
+func TestQueryRows(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	rows, err := db.QueryRows(context.Background(),
+		`SELECT SingerID, FirstName, LastName FROM Singers WHERE SingerID > @id ORDER BY SingerID`, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []Singer
+	for rows.Next() {
+		var s Singer
+		if err := rows.StructScan(&s); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 || got[0] != allSingers[2] || got[2] != allSingers[4] {
+		t.Fatalf("unexpected rows %+v", got)
+	}
+	if rows.Next() {
+		t.Fatal("Next after exhaustion should return false")
+	}
+}
+
+func TestSelectEach(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	var n int
+	err := spansqlx.SelectEach(context.Background(), db,
+		`SELECT SingerID, AlbumID, AlbumTitle FROM Albums WHERE SingerID = @id`, []interface{}{2},
+		func(a *Album) error {
+			if a.SingerID != 2 {
+				t.Errorf("unexpected album %+v", a)
+			}
+			n++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("got %d albums, want 3", n)
+	}
+
+	stop := errors.New("stop")
+	n = 0
+	err = spansqlx.SelectEach(context.Background(), db,
+		`SELECT SingerID FROM Singers`, nil,
+		func(id *int64) error {
+			n++
+			return stop
+		})
+	if err != stop || n != 1 {
+		t.Fatalf("got (%v, %d), want callback error after first row", err, n)
+	}
+}
@@ -0,0 +1,430 @@
+// Package spansqlxtest is an in-memory fake of spanner for unit tests of
+// code using spansqlx, which do not need the emulator.
+//
+// New returns a *spansqlx.DB backed by a fake spanner server. Tests register
+// the statements they expect, by SQL pattern and parameters, with the rows
+// or the error to return:
+//
+//	db, fake := spansqlxtest.New(t)
+//	fake.ExpectQuery(`SELECT .* FROM Singers WHERE SingerID = @id`).
+//		WithParams(map[string]interface{}{"id": 1}).
+//		WillReturnRows(spansqlxtest.NewRows("SingerID", "Name").AddRow(int64(1), "Marc"))
+//	fake.ExpectExec(`UPDATE Singers`).WillAbort()
+//	fake.ExpectExec(`UPDATE Singers`).WillReturnRowsAffected(1)
+//
+// A statement matches the first expectation not met yet, in order of
+// registration, whose pattern matches its SQL. Statements which match no
+// expectation fail. Commits succeed unless an ExpectCommit says otherwise.
+// The expectations must all be met by the end of the test.
+package spansqlxtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/internal"
+	"google.golang.org/api/option"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+)
+
+// Database is the database path of the fake.
+const Database = "projects/fake/instances/fake/databases/fake"
+
+// Fake is a fake spanner server, which records the statements and
+// mutations it is sent.
+type Fake struct {
+	mu           sync.Mutex
+	expectations []expectation
+	statements   []spanner.Statement
+	mutations    []Mutation
+	unexpected   []string
+	txs          map[string]txKind
+	seq          int
+}
+
+// New returns a *spansqlx.DB of opts backed by a new Fake. The fake is
+// stopped at the end of the test, which fails if the expectations of the
+// fake were not met.
+func New(t testing.TB, opts ...spansqlx.Option) (*spansqlx.DB, *Fake) {
+	t.Helper()
+
+	f := &Fake{txs: make(map[string]txKind)}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	sppb.RegisterSpannerServer(srv, &server{f: f})
+	go srv.Serve(lis)
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "fake", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := spanner.ClientConfig{SessionPoolConfig: spanner.DefaultSessionPoolConfig}
+	config.SessionPoolConfig.MinOpened = 1
+	client, err := spanner.NewClientWithConfig(ctx, Database, config, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		srv.Stop()
+		if err := f.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return spansqlx.NewDb(ctx, client, opts...), f
+}
+
+// ExpectQuery expects a query whose SQL matches the regular expression
+// pattern. It returns no rows unless told otherwise.
+func (f *Fake) ExpectQuery(pattern string) *ExpectedQuery {
+	e := &ExpectedQuery{expectedBase: newExpectation(kindQuery, pattern)}
+	f.expect(e)
+	return e
+}
+
+// ExpectExec expects a DML statement whose SQL matches the regular
+// expression pattern. It affects no rows unless told otherwise.
+func (f *Fake) ExpectExec(pattern string) *ExpectedExec {
+	e := &ExpectedExec{expectedBase: newExpectation(kindExec, pattern)}
+	f.expect(e)
+	return e
+}
+
+// ExpectCommit expects a commit, e.g. to fail it. Commits which are not
+// expected succeed.
+func (f *Fake) ExpectCommit() *ExpectedCommit {
+	e := &ExpectedCommit{expectedBase: newExpectation(kindCommit, "")}
+	f.expect(e)
+	return e
+}
+
+func (f *Fake) expect(e expectation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, e)
+}
+
+// ExpectationsWereMet returns an error if an expectation was not met, or
+// if the fake was sent a statement which was not expected.
+func (f *Fake) ExpectationsWereMet() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var msgs []string
+	for _, e := range f.expectations {
+		if !e.base().met {
+			msgs = append(msgs, "expectation not met: "+e.base().String())
+		}
+	}
+	for _, sql := range f.unexpected {
+		msgs = append(msgs, "unexpected statement: "+sql)
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("spansqlxtest: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// Statements returns the statements sent to the fake, including those of
+// aborted transactions. Parameters are spanner.GenericColumnValue values,
+// which Decode into Go values.
+func (f *Fake) Statements() []spanner.Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]spanner.Statement(nil), f.statements...)
+}
+
+// Mutations returns the mutations of the committed transactions.
+func (f *Fake) Mutations() []Mutation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Mutation(nil), f.mutations...)
+}
+
+// Mutation is a mutation committed to the fake.
+type Mutation struct {
+	// Op is insert, update, insert_or_update, replace or delete.
+	Op      string
+	Table   string
+	Columns []string
+	// Values of the rows written, as encoded by spanner, e.g. INT64 and
+	// TIMESTAMP values are strings.
+	Values [][]interface{}
+	// Keys of the rows deleted, encoded as Values. AllKeys is set by
+	// spanner.AllKeys.
+	Keys    [][]interface{}
+	AllKeys bool
+}
+
+type kind int
+
+const (
+	kindQuery kind = iota
+	kindExec
+	kindCommit
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindQuery:
+		return "query"
+	case kindExec:
+		return "exec"
+	}
+	return "commit"
+}
+
+type expectation interface {
+	base() *expectedBase
+}
+
+// expectedBase is what expectations have in common.
+type expectedBase struct {
+	kind    kind
+	pattern *regexp.Regexp
+	params  map[string]interface{}
+	err     error
+	met     bool
+}
+
+func newExpectation(k kind, pattern string) *expectedBase {
+	return &expectedBase{kind: k, pattern: regexp.MustCompile(pattern)}
+}
+
+func (e *expectedBase) base() *expectedBase {
+	return e
+}
+
+func (e *expectedBase) String() string {
+	if e.kind == kindCommit {
+		return "commit"
+	}
+	if e.params != nil {
+		return fmt.Sprintf("%s %q with %v", e.kind, e.pattern, e.params)
+	}
+	return fmt.Sprintf("%s %q", e.kind, e.pattern)
+}
+
+// matches reports whether the statement sql of kind k, with params, meets
+// e.
+func (e *expectedBase) matches(k kind, sql string, params *structpb.Struct) bool {
+	if e.met || e.kind != k || !e.pattern.MatchString(sql) {
+		return false
+	}
+	if e.params == nil {
+		return true
+	}
+	if len(e.params) != len(params.GetFields()) {
+		return false
+	}
+	for name, v := range e.params {
+		got, ok := params.GetFields()[name]
+		if !ok {
+			return false
+		}
+		want, err := encodeValue(v)
+		if err != nil || !proto.Equal(want.Value, got) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpectedQuery is an expected query.
+type ExpectedQuery struct {
+	*expectedBase
+	rows *Rows
+}
+
+// WithParams expects the query to have exactly params, compared as encoded
+// by spanner, e.g. an int is an INT64.
+func (e *ExpectedQuery) WithParams(params map[string]interface{}) *ExpectedQuery {
+	e.params = params
+	return e
+}
+
+// WillReturnRows returns rows as the result of the query.
+func (e *ExpectedQuery) WillReturnRows(rows *Rows) *ExpectedQuery {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError fails the query with err, whose gRPC code is kept.
+func (e *ExpectedQuery) WillReturnError(err error) *ExpectedQuery {
+	e.err = err
+	return e
+}
+
+// WillAbort fails the query as aborted, so that spanner retries its
+// read-write transaction.
+func (e *ExpectedQuery) WillAbort() *ExpectedQuery {
+	return e.WillReturnError(errAborted)
+}
+
+// ExpectedExec is an expected DML statement.
+type ExpectedExec struct {
+	*expectedBase
+	rowsAffected int64
+}
+
+// WithParams expects the statement to have exactly params, compared as
+// encoded by spanner, e.g. an int is an INT64.
+func (e *ExpectedExec) WithParams(params map[string]interface{}) *ExpectedExec {
+	e.params = params
+	return e
+}
+
+// WillReturnRowsAffected returns n as the rows affected by the statement.
+func (e *ExpectedExec) WillReturnRowsAffected(n int64) *ExpectedExec {
+	e.rowsAffected = n
+	return e
+}
+
+// WillReturnError fails the statement with err, whose gRPC code is kept.
+func (e *ExpectedExec) WillReturnError(err error) *ExpectedExec {
+	e.err = err
+	return e
+}
+
+// WillAbort fails the statement as aborted, so that spanner retries its
+// read-write transaction.
+func (e *ExpectedExec) WillAbort() *ExpectedExec {
+	return e.WillReturnError(errAborted)
+}
+
+// ExpectedCommit is an expected commit.
+type ExpectedCommit struct {
+	*expectedBase
+}
+
+// WillReturnError fails the commit with err, whose gRPC code is kept.
+func (e *ExpectedCommit) WillReturnError(err error) *ExpectedCommit {
+	e.err = err
+	return e
+}
+
+// WillAbort fails the commit as aborted, so that spanner retries the
+// read-write transaction.
+func (e *ExpectedCommit) WillAbort() *ExpectedCommit {
+	return e.WillReturnError(errAborted)
+}
+
+var errAborted = status.Error(codes.Aborted, "spansqlxtest: transaction aborted")
+
+// statementKind returns the kind of the statement sql.
+func statementKind(sql string) kind {
+	switch keyword, _ := internal.StatementKeyword(sql); keyword {
+	case "INSERT", "UPDATE", "DELETE":
+		return kindExec
+	}
+	return kindQuery
+}
+
+// match records the statement of sql and params, and returns the
+// expectation it meets.
+func (f *Fake) match(sql string, params *structpb.Struct, types map[string]*sppb.Type) (expectation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stmt := spanner.NewStatement(sql)
+	for name, v := range params.GetFields() {
+		stmt.Params[name] = spanner.GenericColumnValue{Type: types[name], Value: v}
+	}
+	f.statements = append(f.statements, stmt)
+
+	k := statementKind(sql)
+	for _, e := range f.expectations {
+		if e.base().matches(k, sql, params) {
+			e.base().met = true
+			return e, nil
+		}
+	}
+
+	f.unexpected = append(f.unexpected, sql)
+	return nil, status.Errorf(codes.Unknown, "spansqlxtest: unexpected %s %q", k, sql)
+}
+
+// commit meets the first commit expectation not met yet, if any, and
+// records the mutations of a successful commit.
+func (f *Fake) commit(ms []*sppb.Mutation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, e := range f.expectations {
+		if b := e.base(); !b.met && b.kind == kindCommit {
+			b.met = true
+			if b.err != nil {
+				return grpcError(b.err)
+			}
+			break
+		}
+	}
+
+	for _, m := range ms {
+		f.mutations = append(f.mutations, newMutation(m))
+	}
+	return nil
+}
+
+// grpcError returns err as a gRPC status error, of code Unknown if err has
+// no status.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func newMutation(m *sppb.Mutation) Mutation {
+	var (
+		op string
+		w  *sppb.Mutation_Write
+	)
+	switch o := m.Operation.(type) {
+	case *sppb.Mutation_Insert:
+		op, w = "insert", o.Insert
+	case *sppb.Mutation_Update:
+		op, w = "update", o.Update
+	case *sppb.Mutation_InsertOrUpdate:
+		op, w = "insert_or_update", o.InsertOrUpdate
+	case *sppb.Mutation_Replace:
+		op, w = "replace", o.Replace
+	case *sppb.Mutation_Delete_:
+		return Mutation{
+			Op:      "delete",
+			Table:   o.Delete.GetTable(),
+			Keys:    listValues(o.Delete.GetKeySet().GetKeys()),
+			AllKeys: o.Delete.GetKeySet().GetAll(),
+		}
+	}
+	return Mutation{Op: op, Table: w.GetTable(), Columns: w.GetColumns(), Values: listValues(w.GetValues())}
+}
+
+func listValues(lists []*structpb.ListValue) [][]interface{} {
+	values := make([][]interface{}, len(lists))
+	for i, l := range lists {
+		values[i] = l.AsSlice()
+	}
+	return values
+}
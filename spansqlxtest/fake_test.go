@@ -0,0 +1,218 @@
+package spansqlxtest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"github.com/reiot101/spansqlx/spansqlxtest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type singer struct {
+	SingerID  int64
+	FirstName string
+	LastName  spanner.NullString
+}
+
+// recorder is a testing.TB whose errors and cleanups are recorded, to test
+// the failures of a fake.
+type recorder struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorder) Error(args ...interface{}) {
+	for _, arg := range args {
+		r.errors = append(r.errors, arg.(error).Error())
+	}
+}
+
+func (r *recorder) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func (r *recorder) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestQuery(t *testing.T) {
+	db, fake := spansqlxtest.New(t)
+	ctx := context.Background()
+
+	fake.ExpectQuery(`SELECT .* FROM Singers WHERE SingerID = @id`).
+		WithParams(map[string]interface{}{"id": 1}).
+		WillReturnRows(spansqlxtest.NewRows("SingerID", "FirstName", "LastName").
+			AddRow(int64(1), "Marc", spanner.NullString{}))
+	fake.ExpectQuery(`SELECT .* FROM Singers`).
+		WillReturnRows(spansqlxtest.NewRows("SingerID", "FirstName", "LastName").
+			AddRow(int64(1), "Marc", "Richards").
+			AddRow(int64(2), "Catalina", "Smith"))
+	fake.ExpectQuery(`SELECT .* FROM Singers`).
+		WillReturnRows(spansqlxtest.NewRows("SingerID", "FirstName", "LastName"))
+	fake.ExpectQuery(`SELECT .* FROM Singers`).
+		WillReturnError(status.Error(codes.NotFound, "table not found"))
+
+	var s singer
+	if err := db.Get(ctx, &s, `SELECT * FROM Singers WHERE SingerID = @id`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if s.SingerID != 1 || s.FirstName != "Marc" || s.LastName.Valid {
+		t.Fatalf("got %+v", s)
+	}
+
+	var ss []singer
+	if err := db.Select(ctx, &ss, `SELECT * FROM Singers`); err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 2 || ss[1].FirstName != "Catalina" || ss[1].LastName.StringVal != "Smith" {
+		t.Fatalf("got %+v", ss)
+	}
+
+	if err := db.Get(ctx, &s, `SELECT * FROM Singers`); !errors.Is(err, spansqlx.ErrNoRows) {
+		t.Fatalf("got error %v, want ErrNoRows", err)
+	}
+	if err := db.Select(ctx, &ss, `SELECT * FROM Singers`); !spansqlx.IsNotFound(err) {
+		t.Fatalf("got error %v, want NotFound", err)
+	}
+
+	stmts := fake.Statements()
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements", len(stmts))
+	}
+	var id int64
+	if err := stmts[0].Params["id"].(spanner.GenericColumnValue).Decode(&id); err != nil || id != 1 {
+		t.Fatalf("got id %d, error %v", id, err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	db, fake := spansqlxtest.New(t)
+	ctx := context.Background()
+
+	fake.ExpectExec(`UPDATE Singers SET FirstName = @name`).
+		WithParams(map[string]interface{}{"name": "Marco", "id": int64(1)}).
+		WillReturnRowsAffected(1)
+	fake.ExpectExec(`DELETE FROM Singers`).
+		WillReturnError(status.Error(codes.FailedPrecondition, "constraint violated"))
+
+	res, err := db.Exec(ctx, `UPDATE Singers SET FirstName = @name WHERE SingerID = @id`, "Marco", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RowsAffected != 1 {
+		t.Fatalf("got %d rows affected", res.RowsAffected)
+	}
+
+	if _, err := db.Exec(ctx, `DELETE FROM Singers WHERE TRUE`); !spansqlx.IsConstraintViolation(err) {
+		t.Fatalf("got error %v, want a constraint violation", err)
+	}
+}
+
+func TestUnexpected(t *testing.T) {
+	r := &recorder{TB: t}
+	db, fake := spansqlxtest.New(r)
+	ctx := context.Background()
+
+	fake.ExpectExec(`UPDATE Singers`).WithParams(map[string]interface{}{"id": 1})
+	fake.ExpectQuery(`SELECT 1`)
+
+	if _, err := db.Exec(ctx, `UPDATE Singers SET FirstName = 'Marc' WHERE SingerID = @id`, 2); err == nil {
+		t.Fatal("expected unexpected statement error")
+	}
+
+	r.cleanup()
+	if len(r.errors) != 1 {
+		t.Fatalf("got errors %q", r.errors)
+	}
+	for _, want := range []string{
+		`expectation not met: exec "UPDATE Singers" with map[id:1]`,
+		`expectation not met: query "SELECT 1"`,
+		`unexpected statement: UPDATE Singers`,
+	} {
+		if !strings.Contains(r.errors[0], want) {
+			t.Errorf("got error %q, want %q", r.errors[0], want)
+		}
+	}
+}
+
+func TestTxPipelineRetry(t *testing.T) {
+	db, fake := spansqlxtest.New(t)
+	ctx := context.Background()
+
+	fake.ExpectExec(`UPDATE Singers`).WillAbort()
+	fake.ExpectExec(`UPDATE Singers`).WillReturnRowsAffected(1)
+	fake.ExpectCommit().WillAbort()
+	fake.ExpectExec(`UPDATE Singers`).WillReturnRowsAffected(1)
+
+	var (
+		attempts  []int
+		committed int
+	)
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		attempts = append(attempts, spansqlx.TxAttempt(ctx))
+		if err := spansqlx.AfterCommit(ctx, func(context.Context) { committed++ }); err != nil {
+			return err
+		}
+		if _, err := db.Exec(ctx, `UPDATE Singers SET FirstName = 'Marc' WHERE SingerID = 1`); err != nil {
+			return err
+		}
+		return db.Insert(ctx, "Singers", &singer{SingerID: 2, FirstName: "Catalina"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 3 || attempts[2] != 3 {
+		t.Fatalf("got attempts %v", attempts)
+	}
+	if committed != 1 {
+		t.Fatalf("got %d after commit calls", committed)
+	}
+	if n := len(fake.Statements()); n != 3 {
+		t.Fatalf("got %d statements", n)
+	}
+
+	ms := fake.Mutations()
+	if len(ms) != 1 {
+		t.Fatalf("got %d mutations", len(ms))
+	}
+	if ms[0].Op != "insert" || ms[0].Table != "Singers" || len(ms[0].Values) != 1 {
+		t.Fatalf("got mutation %+v", ms[0])
+	}
+	values := make(map[string]interface{})
+	for i, col := range ms[0].Columns {
+		values[col] = ms[0].Values[0][i]
+	}
+	if values["SingerID"] != "2" || values["FirstName"] != "Catalina" || values["LastName"] != nil {
+		t.Fatalf("got values %v", values)
+	}
+}
+
+func TestCommitError(t *testing.T) {
+	db, fake := spansqlxtest.New(t)
+	ctx := context.Background()
+
+	fake.ExpectCommit().WillReturnError(status.Error(codes.AlreadyExists, "row exists"))
+
+	err := db.Insert(ctx, "Singers", &singer{SingerID: 1, FirstName: "Marc"})
+	if !spansqlx.IsAlreadyExists(err) {
+		t.Fatalf("got error %v, want AlreadyExists", err)
+	}
+	if ms := fake.Mutations(); len(ms) != 0 {
+		t.Fatalf("got mutations %+v of a failed commit", ms)
+	}
+
+	if err := db.Delete(ctx, "Singers", spanner.AllKeys()); err != nil {
+		t.Fatal(err)
+	}
+	if ms := fake.Mutations(); len(ms) != 1 || ms[0].Op != "delete" || !ms[0].AllKeys {
+		t.Fatalf("got mutations %+v", ms)
+	}
+}
@@ -0,0 +1,68 @@
+package spansqlxtest
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+)
+
+// Rows are the canned rows of an ExpectedQuery.
+type Rows struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// NewRows returns empty rows of columns. Column types are those of the
+// values of the first row, or STRING without rows.
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row of values, in the order of the columns. Values are
+// encoded as spanner parameters, NULL values must be typed, e.g.
+// spanner.NullString{}.
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	r.rows = append(r.rows, values)
+	return r
+}
+
+// encode returns the metadata and the values of the rows.
+func (r *Rows) encode() (*sppb.ResultSetMetadata, [][]*structpb.Value, error) {
+	fields := make([]*sppb.StructType_Field, len(r.columns))
+	for i, name := range r.columns {
+		fields[i] = &sppb.StructType_Field{Name: name, Type: &sppb.Type{Code: sppb.TypeCode_STRING}}
+	}
+
+	values := make([][]*structpb.Value, len(r.rows))
+	for i, row := range r.rows {
+		if len(row) != len(r.columns) {
+			return nil, nil, fmt.Errorf("spansqlxtest: row %d has %d values for %d columns", i, len(row), len(r.columns))
+		}
+		values[i] = make([]*structpb.Value, len(row))
+		for j, v := range row {
+			col, err := encodeValue(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("spansqlxtest: row %d, column %s: %w", i, r.columns[j], err)
+			}
+			if i == 0 {
+				fields[j].Type = col.Type
+			}
+			values[i][j] = col.Value
+		}
+	}
+
+	return &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: fields}}, values, nil
+}
+
+// encodeValue returns v as encoded by spanner.
+func encodeValue(v interface{}) (spanner.GenericColumnValue, error) {
+	var col spanner.GenericColumnValue
+	row, err := spanner.NewRow([]string{"v"}, []interface{}{v})
+	if err != nil {
+		return col, err
+	}
+	err = row.Column(0, &col)
+	return col, err
+}
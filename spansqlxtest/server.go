@@ -0,0 +1,182 @@
+package spansqlxtest
+
+import (
+	"context"
+	"fmt"
+
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// txKind is the kind of a transaction begun on the fake.
+type txKind int
+
+const (
+	txReadWrite txKind = iota
+	txReadOnly
+	txPartitioned
+)
+
+// server is the spanner gRPC service of a Fake. Sessions and transactions
+// are only given ids, every statement is answered by the expectations.
+type server struct {
+	sppb.UnimplementedSpannerServer
+	f *Fake
+}
+
+func (s *server) newID(prefix string) string {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.f.seq++
+	return fmt.Sprintf("%s-%d", prefix, s.f.seq)
+}
+
+func (s *server) CreateSession(ctx context.Context, req *sppb.CreateSessionRequest) (*sppb.Session, error) {
+	return &sppb.Session{Name: req.GetDatabase() + "/sessions/" + s.newID("session")}, nil
+}
+
+func (s *server) BatchCreateSessions(ctx context.Context, req *sppb.BatchCreateSessionsRequest) (*sppb.BatchCreateSessionsResponse, error) {
+	sessions := make([]*sppb.Session, req.GetSessionCount())
+	for i := range sessions {
+		sessions[i] = &sppb.Session{Name: req.GetDatabase() + "/sessions/" + s.newID("session")}
+	}
+	return &sppb.BatchCreateSessionsResponse{Session: sessions}, nil
+}
+
+func (s *server) GetSession(ctx context.Context, req *sppb.GetSessionRequest) (*sppb.Session, error) {
+	return &sppb.Session{Name: req.GetName()}, nil
+}
+
+func (s *server) DeleteSession(ctx context.Context, req *sppb.DeleteSessionRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func (s *server) BeginTransaction(ctx context.Context, req *sppb.BeginTransactionRequest) (*sppb.Transaction, error) {
+	return s.begin(req.GetOptions()), nil
+}
+
+// begin a transaction of opts.
+func (s *server) begin(opts *sppb.TransactionOptions) *sppb.Transaction {
+	k := txReadWrite
+	switch {
+	case opts.GetReadOnly() != nil:
+		k = txReadOnly
+	case opts.GetPartitionedDml() != nil:
+		k = txPartitioned
+	}
+
+	id := s.newID("tx")
+	s.f.mu.Lock()
+	s.f.txs[id] = k
+	s.f.mu.Unlock()
+
+	tx := &sppb.Transaction{Id: []byte(id)}
+	if k == txReadOnly {
+		tx.ReadTimestamp = timestamppb.Now()
+	}
+	return tx
+}
+
+func (s *server) Commit(ctx context.Context, req *sppb.CommitRequest) (*sppb.CommitResponse, error) {
+	if err := s.f.commit(req.GetMutations()); err != nil {
+		return nil, err
+	}
+	resp := &sppb.CommitResponse{CommitTimestamp: timestamppb.Now()}
+	if req.GetReturnCommitStats() {
+		resp.CommitStats = &sppb.CommitResponse_CommitStats{MutationCount: int64(len(req.GetMutations()))}
+	}
+	return resp, nil
+}
+
+func (s *server) Rollback(ctx context.Context, req *sppb.RollbackRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+// result is the result of a statement.
+type result struct {
+	metadata *sppb.ResultSetMetadata
+	rows     [][]*structpb.Value
+	stats    *sppb.ResultSetStats
+}
+
+// execute answers the statement of req with its expectation.
+func (s *server) execute(req *sppb.ExecuteSqlRequest) (*result, error) {
+	e, err := s.f.match(req.GetSql(), req.GetParams(), req.GetParamTypes())
+	if err != nil {
+		return nil, err
+	}
+	if err := e.base().err; err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &result{metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{}}}
+	switch e := e.(type) {
+	case *ExpectedQuery:
+		if e.rows != nil {
+			if res.metadata, res.rows, err = e.rows.encode(); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+	case *ExpectedExec:
+		s.f.mu.Lock()
+		partitioned := s.f.txs[string(req.GetTransaction().GetId())] == txPartitioned
+		s.f.mu.Unlock()
+		if partitioned {
+			res.stats = &sppb.ResultSetStats{RowCount: &sppb.ResultSetStats_RowCountLowerBound{RowCountLowerBound: e.rowsAffected}}
+		} else {
+			res.stats = &sppb.ResultSetStats{RowCount: &sppb.ResultSetStats_RowCountExact{RowCountExact: e.rowsAffected}}
+		}
+	}
+
+	if begin := req.GetTransaction().GetBegin(); begin != nil {
+		res.metadata.Transaction = s.begin(begin)
+	}
+	return res, nil
+}
+
+func (s *server) ExecuteSql(ctx context.Context, req *sppb.ExecuteSqlRequest) (*sppb.ResultSet, error) {
+	res, err := s.execute(req)
+	if err != nil {
+		return nil, err
+	}
+	rs := &sppb.ResultSet{Metadata: res.metadata, Stats: res.stats}
+	for _, row := range res.rows {
+		rs.Rows = append(rs.Rows, &structpb.ListValue{Values: row})
+	}
+	return rs, nil
+}
+
+func (s *server) ExecuteStreamingSql(req *sppb.ExecuteSqlRequest, stream sppb.Spanner_ExecuteStreamingSqlServer) error {
+	res, err := s.execute(req)
+	if err != nil {
+		return err
+	}
+	prs := &sppb.PartialResultSet{Metadata: res.metadata, Stats: res.stats}
+	for _, row := range res.rows {
+		prs.Values = append(prs.Values, row...)
+	}
+	return stream.Send(prs)
+}
+
+func (s *server) ExecuteBatchDml(ctx context.Context, req *sppb.ExecuteBatchDmlRequest) (*sppb.ExecuteBatchDmlResponse, error) {
+	resp := &sppb.ExecuteBatchDmlResponse{Status: &rpcstatus.Status{}}
+	for _, stmt := range req.GetStatements() {
+		res, err := s.execute(&sppb.ExecuteSqlRequest{
+			Transaction: req.GetTransaction(),
+			Sql:         stmt.GetSql(),
+			Params:      stmt.GetParams(),
+			ParamTypes:  stmt.GetParamTypes(),
+		})
+		if err != nil {
+			resp.Status = status.Convert(err).Proto()
+			break
+		}
+		resp.ResultSets = append(resp.ResultSets, &sppb.ResultSet{Metadata: res.metadata, Stats: res.stats})
+	}
+	return resp, nil
+}
@@ -0,0 +1,262 @@
+package spansqlx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrMaxAttempts is returned by a TxPipeline aborted by spanner more times
+// than allowed by WithMaxAttempts. The returned error wraps the error of the
+// last attempt as well, errors.Is matches both.
+var ErrMaxAttempts = errors.New("spansqlx: transaction aborted too many times")
+
+// maxAttemptsError is the ErrMaxAttempts of a TxPipeline whose last attempt
+// failed with err.
+type maxAttemptsError struct {
+	attempts int
+	err      error
+}
+
+func (e *maxAttemptsError) Error() string {
+	return fmt.Sprintf("%v: %d attempts: %v", ErrMaxAttempts, e.attempts, e.err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *maxAttemptsError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is ErrMaxAttempts.
+func (e *maxAttemptsError) Is(target error) bool {
+	return target == ErrMaxAttempts
+}
+
+// lastAttemptError hides the aborted error of the last attempt from
+// spanner, which would retry the transaction, until the transaction is done.
+type lastAttemptError struct {
+	err error
+}
+
+func (e *lastAttemptError) Error() string {
+	return e.err.Error()
+}
+
+// ErrNoTxPipeline is returned by AfterCommit outside of a TxPipeline.
+var ErrNoTxPipeline = errors.New("spansqlx: no TxPipeline in context")
+
+// TxOption configures a TxPipeline.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	maxAttempts int
+	timeout     time.Duration
+	onRetry     []func(ctx context.Context, attempt int)
+	commitStats bool
+}
+
+// WithMaxAttempts fails the TxPipeline with ErrMaxAttempts, and the error
+// of the last attempt, once spanner aborted it n times. Spanner retries
+// aborted transactions until the context is done by default.
+func WithMaxAttempts(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithTxTimeout bounds the TxPipeline, all attempts included, to d.
+func WithTxTimeout(d time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.timeout = d
+	}
+}
+
+// WithOnRetry calls fn before the callback of the TxPipeline is run again,
+// after spanner aborted the transaction. attempt is the attempt about to
+// run, starting at 2.
+func WithOnRetry(fn func(ctx context.Context, attempt int)) TxOption {
+	return func(o *txOptions) {
+		o.onRetry = append(o.onRetry, fn)
+	}
+}
+
+// WithCommitStats requests the commit statistics of the transaction, which
+// are returned in the CommitResult of TxPipelineWithResult.
+func WithCommitStats() TxOption {
+	return func(o *txOptions) {
+		o.commitStats = true
+	}
+}
+
+// CommitResult is the result of a committed TxPipelineWithResult.
+type CommitResult struct {
+	// CommitTimestamp is the timestamp of the transaction, the value written
+	// in place of spanner.CommitTimestamp.
+	CommitTimestamp time.Time
+	// CommitStats is only set when requested WithCommitStats.
+	CommitStats *CommitStats
+}
+
+// CommitStats are the commit statistics of a transaction.
+type CommitStats struct {
+	// MutationCount is the number of mutations of the transaction, counted
+	// per cell written, as limited by spanner per commit.
+	MutationCount int64
+}
+
+// TxPipeline is ReadWriteTransaction wrap.
+// The callback is run again if spanner aborts the transaction, TxAttempt
+// returns the attempt from its context. Side effects of the callback which
+// must happen once should be registered with AfterCommit.
+func (d *DB) TxPipeline(ctx context.Context, callback func(ctx context.Context) error, opts ...TxOption) error {
+	_, err := d.TxPipelineWithResult(ctx, callback, opts...)
+	return err
+}
+
+// TxPipelineWithResult is TxPipeline returning the commit timestamp, and the
+// commit statistics if requested WithCommitStats.
+func (d *DB) TxPipelineWithResult(ctx context.Context, callback func(ctx context.Context) error, opts ...TxOption) (CommitResult, error) {
+	var o txOptions
+	for i := range opts {
+		opts[i](&o)
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	var (
+		p    *txPipeline
+		resp spanner.CommitResponse
+	)
+	ctx, op, err := d.startOperation(ctx, OpTransaction, TxReadWrite, nil, nil)
+	if err == nil {
+		resp, err = d.readWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+			attempt := attemptContext(ctx)
+			if o.maxAttempts > 0 && attempt > o.maxAttempts {
+				// the callback of the last attempt succeeded, its commit
+				// was aborted.
+				return &lastAttemptError{err: status.Error(codes.Aborted, "commit aborted")}
+			}
+			op.Attempt = attempt
+			op.TxID = txIDContext(ctx)
+
+			if attempt > 1 {
+				for _, fn := range o.onRetry {
+					fn(ctx, attempt)
+				}
+			}
+
+			// the callbacks of aborted attempts are dropped.
+			p = &txPipeline{}
+			err := callback(SetTxContext(context.WithValue(ctx, txPipelineContextKey, p), tx))
+			if o.maxAttempts > 0 && attempt >= o.maxAttempts && ErrCode(err) == codes.Aborted {
+				return &lastAttemptError{err: err}
+			}
+			return err
+		}, spanner.TransactionOptions{
+			CommitOptions: spanner.CommitOptions{ReturnCommitStats: o.commitStats},
+		})
+
+		var last *lastAttemptError
+		if errors.As(err, &last) {
+			err = &maxAttemptsError{attempts: o.maxAttempts, err: last.err}
+		}
+	}
+	err = d.endOperation(ctx, op, 0, err)
+	if err != nil {
+		return CommitResult{}, err
+	}
+
+	if p != nil {
+		p.mu.Lock()
+		afterCommit := p.afterCommit
+		p.mu.Unlock()
+		for _, fn := range afterCommit {
+			fn(ctx)
+		}
+	}
+
+	res := CommitResult{CommitTimestamp: resp.CommitTs}
+	if resp.CommitStats != nil {
+		res.CommitStats = &CommitStats{MutationCount: resp.CommitStats.MutationCount}
+	}
+	return res, nil
+}
+
+// txPipeline is the state of an attempt of a TxPipeline.
+type txPipeline struct {
+	// mu guards afterCommit, the callback may register from goroutines.
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context)
+}
+
+// AfterCommit registers fn to run once the TxPipeline of ctx commits, e.g.
+// to publish events. fn runs exactly once, after TxPipeline returns from
+// spanner, and never if the transaction fails. Callbacks run in order of
+// registration.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) error {
+	if ctx == nil {
+		return ErrNoTxPipeline
+	}
+	p, ok := ctx.Value(txPipelineContextKey).(*txPipeline)
+	if !ok {
+		return ErrNoTxPipeline
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.afterCommit = append(p.afterCommit, fn)
+	return nil
+}
+
+// TxAttempt returns the attempt of the read-write transaction of ctx,
+// starting at 1, or 0 outside of a TxPipeline.
+func TxAttempt(ctx context.Context) int {
+	return attemptContext(ctx)
+}
+
+// implicitTransaction runs fn in the read-write transaction of a statement
+// executed outside of a TxPipeline. It is an OpTransaction as well, so that
+// interceptors see its commit, aborts and retries.
+func (d *DB) implicitTransaction(ctx context.Context, fn func(ctx context.Context, tx *spanner.ReadWriteTransaction) error) error {
+	ctx, op, err := d.startOperation(ctx, OpTransaction, TxReadWrite, nil, nil)
+	if err == nil {
+		_, err = d.readWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+			op.Attempt = attemptContext(ctx)
+			op.TxID = txIDContext(ctx)
+			return fn(ctx, tx)
+		}, spanner.TransactionOptions{})
+	}
+	return d.endOperation(ctx, op, 0, err)
+}
+
+// readWriteTransaction runs fn in a read-write transaction, which spanner
+// retries when aborted. The id of the transaction and the attempt are set in
+// the context of fn.
+func (d *DB) readWriteTransaction(ctx context.Context, fn func(ctx context.Context, tx *spanner.ReadWriteTransaction) error, opts spanner.TransactionOptions) (spanner.CommitResponse, error) {
+	ctx = context.WithValue(ctx, txIDContextKey, newTxID())
+	var attempt int
+	return d.db.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		attempt++
+		return fn(context.WithValue(ctx, attemptContextKey, attempt), tx)
+	}, opts)
+}
+
+// newTxID returns a random hex encoded transaction id.
+func newTxID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
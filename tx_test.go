@@ -0,0 +1,163 @@
+package spansqlx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/reiot101/spansqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCommitTimestamp(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	type Event struct {
+		EventID   int64
+		Name      string
+		UpdatedAt time.Time `db:"UpdatedAt,commit_timestamp"`
+	}
+
+	updatedAt := func() (ts time.Time) {
+		t.Helper()
+		if err := db.Get(ctx, &ts, `SELECT UpdatedAt FROM Events WHERE EventID = 1`); err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	res, err := db.TxPipelineWithResult(ctx, func(ctx context.Context) error {
+		return db.Insert(ctx, "Events", Event{EventID: 1, Name: "created"})
+	}, spansqlx.WithCommitStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CommitTimestamp.IsZero() || !updatedAt().Equal(res.CommitTimestamp) {
+		t.Fatalf("got commit timestamp %v, updated at %v", res.CommitTimestamp, updatedAt())
+	}
+
+	// spannertest does not evaluate PENDING_COMMIT_TIMESTAMP() in DML, named
+	// statements are covered by the internal tests.
+	res, err = db.TxPipelineWithResult(ctx, func(ctx context.Context) error {
+		return db.Update(ctx, "Events", Event{EventID: 1, Name: "updated"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CommitStats != nil {
+		t.Fatalf("got commit stats %+v without WithCommitStats", res.CommitStats)
+	}
+	if !updatedAt().Equal(res.CommitTimestamp) {
+		t.Fatalf("got commit timestamp %v, updated at %v", res.CommitTimestamp, updatedAt())
+	}
+
+	// queries bind the value of the tagged field.
+	var events []Event
+	if err := db.NamedSelect(ctx, &events, `SELECT * FROM Events WHERE UpdatedAt > @UpdatedAt`,
+		Event{UpdatedAt: res.CommitTimestamp.Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Name != "updated" {
+		t.Fatalf("got events %+v", events)
+	}
+}
+
+func TestTxPipelineRetries(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	aborted := spanner.ToSpannerError(status.Error(codes.Aborted, "aborted"))
+
+	var (
+		attempts  []int
+		retries   []int
+		committed []int
+	)
+	err := db.TxPipeline(ctx, func(ctx context.Context) error {
+		attempt := spansqlx.TxAttempt(ctx)
+		attempts = append(attempts, attempt)
+		if err := spansqlx.AfterCommit(ctx, func(ctx context.Context) {
+			committed = append(committed, attempt)
+		}); err != nil {
+			return err
+		}
+		if attempt < 3 {
+			return aborted
+		}
+		_, err := db.Exec(ctx, `UPDATE Singers SET LastName = 'Retried' WHERE SingerID = 1`)
+		return err
+	}, spansqlx.WithOnRetry(func(ctx context.Context, attempt int) {
+		retries = append(retries, attempt)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(attempts, retries, committed) != "[1 2 3] [2 3] [3]" {
+		t.Fatalf("got attempts %v, retries %v, after commit %v", attempts, retries, committed)
+	}
+
+	// never committed, the second abort is the last.
+	committed, attempts = nil, nil
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		attempts = append(attempts, spansqlx.TxAttempt(ctx))
+		if err := spansqlx.AfterCommit(ctx, func(ctx context.Context) {
+			committed = append(committed, 1)
+		}); err != nil {
+			return err
+		}
+		return aborted
+	}, spansqlx.WithMaxAttempts(2))
+	if !errors.Is(err, spansqlx.ErrMaxAttempts) || !strings.Contains(err.Error(), aborted.Error()) ||
+		len(committed) != 0 || fmt.Sprint(attempts) != "[1 2]" {
+		t.Fatalf("got error %v, attempts %v and after commit %v", err, attempts, committed)
+	}
+	// the error of the last attempt is wrapped.
+	var se *spanner.Error
+	var e *spansqlx.Error
+	if !errors.As(err, &se) || se != aborted || spansqlx.ErrCode(err) != codes.Aborted ||
+		!errors.As(err, &e) || e.Op != spansqlx.OpTransaction || e.Code != codes.Aborted {
+		t.Fatalf("got error %#v, want the aborted error of the last attempt", err)
+	}
+
+	// callbacks may be registered concurrently.
+	var n int32
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				spansqlx.AfterCommit(ctx, func(context.Context) { atomic.AddInt32(&n, 1) })
+			}()
+		}
+		wg.Wait()
+		return nil
+	})
+	if err != nil || n != 10 {
+		t.Fatalf("got %d after commit calls, error %v", n, err)
+	}
+
+	err = db.TxPipeline(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, spansqlx.WithTxTimeout(10*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected deadline error")
+	}
+
+	if err := spansqlx.AfterCommit(ctx, func(context.Context) {}); !errors.Is(err, spansqlx.ErrNoTxPipeline) {
+		t.Fatalf("got error %v, want ErrNoTxPipeline", err)
+	}
+	// nor is a nil context.
+	if err := spansqlx.AfterCommit(nil, func(context.Context) {}); !errors.Is(err, spansqlx.ErrNoTxPipeline) {
+		t.Fatalf("got error %v, want ErrNoTxPipeline", err)
+	}
+}